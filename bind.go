@@ -0,0 +1,160 @@
+package reflekt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// PathParams extracts the path parameters of a request, eg from the context of a router
+type PathParams func(r *http.Request) map[string]string
+
+// BindError aggregates the errors of all fields which could not be bound
+type BindError struct {
+	Fields []*FieldError
+}
+
+func (this *BindError) Error() string {
+	msgs := make([]string, len(this.Fields))
+	for i, f := range this.Fields {
+		msgs[i] = f.Error()
+	}
+	return "Invalid request: " + strings.Join(msgs, ", ")
+}
+
+// Binder fills structs from HTTP requests
+type Binder struct {
+	filler *StructFiller
+	params PathParams
+}
+
+// NewBinder creates a binder using the extractor for path parameters, which can be nil, and a
+// StructFiller reading keys from the `json` tag
+func NewBinder(params PathParams) *Binder {
+	return &Binder{
+		filler: NewStructFiller().UseTag("json"),
+		params: params,
+	}
+}
+
+// UseFiller sets the StructFiller used to fill the structs
+func (this *Binder) UseFiller(filler *StructFiller) *Binder {
+	this.filler = filler
+	return this
+}
+
+// mergeMaps merges src into dst, recursively for maps contained in both
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sm, sok := v.(map[string]interface{})
+		dm, dok := dst[k].(map[string]interface{})
+		if sok && dok {
+			mergeMaps(dm, sm)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// body decodes a JSON or form body into a map
+func (this *Binder) body(r *http.Request) (map[string]interface{}, error) {
+	if r.Body == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return nil, nil
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+		d := json.NewDecoder(r.Body)
+		d.UseNumber()
+		var v interface{}
+		if err := d.Decode(&v); err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("Cannot decode JSON body: %s", err)
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Expected JSON object body, got %T", v)
+		}
+		return m, nil
+	case ct == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		return ParseQuery(r.PostForm)
+	case ct == "multipart/form-data":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		return ParseQuery(r.PostForm)
+	}
+	return nil, nil
+}
+
+// headers adds the headers of fields with a `header` tag to the map
+func (this *Binder) headers(t reflect.Type, h http.Header, d map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+			this.headers(ft.Type, h, d)
+			continue
+		}
+		name := ft.Tag.Get("header")
+		names := this.filler.fieldNames(ft)
+		if name == "" || name == "-" || ft.PkgPath != "" || len(names) == 0 {
+			continue
+		} else if vs := h[http.CanonicalHeaderKey(name)]; len(vs) == 1 {
+			d[names[0]] = vs[0]
+		} else if len(vs) > 1 {
+			l := make([]interface{}, len(vs))
+			for i, v := range vs {
+				l[i] = v
+			}
+			d[names[0]] = l
+		}
+	}
+}
+
+// Bind fills the struct from the request. Values are merged from the JSON or form body, the query,
+// the path parameters and, for fields with a `header` tag, the headers, each overriding the values
+// of the previous sources. Values are cast leniently, but strings given for numeric or bool fields
+// must be parsable as such. Returns a *BindError listing all fields which could not be filled.
+func (this *Binder) Bind(r *http.Request, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Expected pointer to struct, got %T", dst)
+	}
+	d, err := this.body(r)
+	if err != nil {
+		return err
+	} else if d == nil {
+		d = make(map[string]interface{})
+	}
+	query, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		return err
+	}
+	mergeMaps(d, query)
+	if this.params != nil {
+		for k, v := range this.params(r) {
+			d[k] = v
+		}
+	}
+	this.headers(rv.Elem().Type(), r.Header, d)
+
+	if errs := this.filler.fillChecked(rv.Elem(), d, "", nil); len(errs) > 0 {
+		return &BindError{errs}
+	}
+	return nil
+}
+
+var defaultBinder = NewBinder(nil)
+
+// Bind fills the struct from the body, query and tagged headers of the request. See Binder.Bind.
+func Bind(r *http.Request, dst interface{}) error {
+	return defaultBinder.Bind(r, dst)
+}
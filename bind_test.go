@@ -0,0 +1,111 @@
+package reflekt
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type tBindMeta struct {
+	RequestID string `header:"X-Request-Id"`
+}
+
+type tBindRequest struct {
+	tBindMeta
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Age     int      `json:"age"`
+	Active  bool     `json:"active"`
+	Tags    []string `json:"tags"`
+	Langs   []string `header:"Accept-Language"`
+	Address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	} `json:"address"`
+}
+
+func testBindParams(r *http.Request) map[string]string {
+	return map[string]string{"id": strings.TrimPrefix(r.URL.Path, "/users/")}
+}
+
+func TestBind(t *testing.T) {
+	Convey("Bind JSON body, query, path and headers", t, func() {
+		r := httptest.NewRequest("POST", "/users/42?age=30&address[zip]=12345", strings.NewReader(`{"name":"foo","age":20,"active":true,"tags":["a"],"address":{"city":"Berlin"}}`))
+		r.Header.Set("Content-Type", "application/json; charset=utf-8")
+		r.Header.Set("X-Request-Id", "abc")
+		r.Header.Add("Accept-Language", "de")
+		r.Header.Add("Accept-Language", "en")
+		req := &tBindRequest{}
+		So(NewBinder(testBindParams).Bind(r, req), ShouldBeNil)
+		So(req.ID, ShouldEqual, 42)
+		So(req.Name, ShouldEqual, "foo")
+		So(req.Age, ShouldEqual, 30)
+		So(req.Active, ShouldBeTrue)
+		So(req.Tags, ShouldResemble, []string{"a"})
+		So(req.RequestID, ShouldEqual, "abc")
+		So(req.Langs, ShouldResemble, []string{"de", "en"})
+		So(req.Address.City, ShouldEqual, "Berlin")
+		So(req.Address.Zip, ShouldEqual, "12345")
+	})
+	Convey("Bind form body", t, func() {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"name": {"foo"}, "tags[]": {"a", "b"}, "active": {"maybe"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req := &tBindRequest{}
+		So(Bind(r, req), ShouldNotBeNil)
+		r = httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"name": {"foo"}, "tags[]": {"a", "b"}, "active": {"on"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		So(Bind(r, req), ShouldBeNil)
+		So(req.Name, ShouldEqual, "foo")
+		So(req.Tags, ShouldResemble, []string{"a", "b"})
+		So(req.Active, ShouldBeTrue)
+	})
+	Convey("Bind multipart form body", t, func() {
+		buf := new(bytes.Buffer)
+		w := multipart.NewWriter(buf)
+		w.WriteField("name", "foo")
+		w.WriteField("age", "7")
+		w.Close()
+		r := httptest.NewRequest("POST", "/", buf)
+		r.Header.Set("Content-Type", w.FormDataContentType())
+		req := &tBindRequest{}
+		So(Bind(r, req), ShouldBeNil)
+		So(req.Name, ShouldEqual, "foo")
+		So(req.Age, ShouldEqual, 7)
+	})
+	Convey("Aggregate field errors", t, func() {
+		r := httptest.NewRequest("GET", "/?age=old&active=maybe&id=1", nil)
+		err := Bind(r, &tBindRequest{})
+		So(err, ShouldHaveSameTypeAs, &BindError{})
+		fields := []string{}
+		for _, f := range err.(*BindError).Fields {
+			fields = append(fields, f.Field)
+		}
+		So(fields, ShouldResemble, []string{"age", "active"})
+		So(err.Error(), ShouldEqual, `Invalid request: age: Expected number, got "old", active: Expected bool, got "maybe"`)
+	})
+	Convey("Bind requests with numeric keys", t, func() {
+		req := &tBindRequest{}
+		So(Bind(httptest.NewRequest("GET", "/?0=x&name=foo", nil), req), ShouldBeNil)
+		So(req.Name, ShouldEqual, "foo")
+
+		r := httptest.NewRequest("POST", "/?1=y", strings.NewReader(url.Values{"0": {"x"}, "name": {"bar"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = &tBindRequest{}
+		So(Bind(r, req), ShouldBeNil)
+		So(req.Name, ShouldEqual, "bar")
+	})
+	Convey("Fail on invalid requests", t, func() {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{broken`))
+		r.Header.Set("Content-Type", "application/json")
+		So(Bind(r, &tBindRequest{}), ShouldNotBeNil)
+		r = httptest.NewRequest("POST", "/", strings.NewReader(`[1]`))
+		r.Header.Set("Content-Type", "application/json")
+		So(Bind(r, &tBindRequest{}), ShouldNotBeNil)
+		So(Bind(httptest.NewRequest("GET", "/", nil), tBindRequest{}), ShouldNotBeNil)
+	})
+}
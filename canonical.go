@@ -0,0 +1,182 @@
+package reflekt
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+type canonicalizer struct {
+	seen map[walkRef]bool
+}
+
+// canonicalInt returns the big integer as int64 or uint64 if it fits
+func canonicalInt(i *big.Int) interface{} {
+	if i.IsInt64() {
+		return i.Int64()
+	} else if i.IsUint64() {
+		return i.Uint64()
+	}
+	return new(big.Int).Set(i)
+}
+
+// canonicalFloat returns floats without fractional part as int64
+func canonicalFloat(f float64) interface{} {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return int64(f)
+	}
+	return f
+}
+
+func (this *canonicalizer) enter(r reflect.Value) (walkRef, error) {
+	ref := walkRef{r.Pointer(), 0, r.Type()}
+	if r.Kind() == reflect.Slice {
+		ref.l = r.Len()
+	}
+	if this.seen[ref] {
+		return ref, fmt.Errorf("Cannot serialize cyclic %s", r.Type())
+	}
+	this.seen[ref] = true
+	return ref, nil
+}
+
+func (this *canonicalizer) value(r reflect.Value) (interface{}, error) {
+	for r.Kind() == reflect.Ptr || r.Kind() == reflect.Interface {
+		if r.IsNil() {
+			return nil, nil
+		} else if r.Kind() == reflect.Ptr {
+			ref, err := this.enter(r)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(this.seen, ref)
+		}
+		r = r.Elem()
+	}
+	if !r.IsValid() {
+		return nil, nil
+	} else if !r.CanInterface() {
+		return nil, fmt.Errorf("Cannot serialize unexported %s", r.Type())
+	}
+
+	switch v := r.Interface().(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case big.Int:
+		return canonicalInt(&v), nil
+	case Value:
+		return this.value(reflect.ValueOf(v.v))
+	case CBORTag:
+		c, err := this.value(reflect.ValueOf(v.Content))
+		return CBORTag{v.Number, c}, err
+	case json.Number:
+		if i, ok := new(big.Int).SetString(string(v), 10); ok {
+			return canonicalInt(i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return canonicalFloat(f), nil
+	}
+
+	k := r.Kind()
+	switch {
+	case k == reflect.Bool || k == reflect.String:
+		return r.Interface(), nil
+	case IsIntKind(k):
+		return r.Int(), nil
+	case IsUintKind(k):
+		return canonicalInt(new(big.Int).SetUint64(r.Uint())), nil
+	case IsFloatKind(k):
+		return canonicalFloat(r.Float()), nil
+	case isListKind(k) && r.Type().Elem().Kind() == reflect.Uint8:
+		b := make([]byte, r.Len())
+		reflect.Copy(reflect.ValueOf(b), r)
+		return b, nil
+	case isListKind(k):
+		if k == reflect.Slice && r.Len() > 0 {
+			ref, err := this.enter(r)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(this.seen, ref)
+		}
+		res := make([]interface{}, r.Len())
+		for i := range res {
+			v, err := this.value(r.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	case k == reflect.Map:
+		if !r.IsNil() {
+			ref, err := this.enter(r)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(this.seen, ref)
+		}
+		res := make(map[interface{}]interface{}, r.Len())
+		for _, key := range r.MapKeys() {
+			ck, err := this.value(key)
+			if err != nil {
+				return nil, err
+			} else if ckr := reflect.ValueOf(ck); ckr.IsValid() && !ckr.Type().Comparable() {
+				return nil, fmt.Errorf("Cannot serialize map key of type %T", ck)
+			} else if _, exists := res[ck]; exists {
+				return nil, fmt.Errorf("Cannot serialize map with duplicate key %v", ck)
+			}
+			cv, err := this.value(r.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			res[ck] = cv
+		}
+		return res, nil
+	case k == reflect.Struct:
+		res := make(map[interface{}]interface{})
+		for name, fv := range valueEntries(r, false, nil) {
+			cv, err := this.value(fv)
+			if err != nil {
+				return nil, err
+			}
+			res[name] = cv
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("Cannot serialize %s", r.Type())
+}
+
+// MarshalCanonical serializes the value deterministically, so that values which differ only in
+// their Go representation produce the same bytes. The output is canonical CBOR (see
+// CBOREncoder.UseCanonical) of the normalized value: pointers and interfaces are dereferenced,
+// all integers and floats without fractional part are written as integers, slices, arrays and nil
+// slices alike as arrays, structs as maps of their exported fields as in StructAsMap, times in
+// UTC and map keys in sorted order. Strings are not converted, so `"1"` and `1` differ. Cyclic
+// values cannot be serialized.
+func MarshalCanonical(v interface{}) ([]byte, error) {
+	c, err := (&canonicalizer{seen: make(map[walkRef]bool)}).value(reflectValue(v))
+	if err != nil {
+		return nil, err
+	}
+	return MarshalCanonicalCBOR(c)
+}
+
+// Hash writes the canonical serialization of the value to the hash and returns the resulting
+// digest, which is equal for values considered equal by MarshalCanonical. Use a new or reset hash
+// to get a digest of the value only.
+func Hash(v interface{}, h hash.Hash) ([]byte, error) {
+	data, err := MarshalCanonical(v)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
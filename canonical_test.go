@@ -0,0 +1,126 @@
+package reflekt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type tCanonicalServer struct {
+	Host  string
+	Port  int
+	Tags  []string
+	Since time.Time
+}
+
+type tCanonicalConfig struct {
+	Name    string
+	Servers []*tCanonicalServer
+	secret  string
+}
+
+type tCanonicalNode struct {
+	Next *tCanonicalNode
+}
+
+var testsCanonicalEqual = []struct {
+	a, b interface{}
+}{
+	{1, int64(1)},
+	{uint8(1), json.Number("1")},
+	{1, 1.0},
+	{float32(0.5), 0.5},
+	{big.NewInt(-3), int16(-3)},
+	{json.Number("18446744073709551616"), tCBORBig("18446744073709551616")},
+	{[]int{1, 2}, [2]uint{1, 2}},
+	{[]string(nil), []interface{}{}},
+	{map[string]int{"a": 1, "b": 2}, map[interface{}]interface{}{"b": 2.0, "a": int8(1)}},
+	{&tCanonicalServer{Host: "x", Port: 80}, map[string]interface{}{"Host": "x", "Port": 80, "Tags": []string{}, "Since": time.Time{}}},
+	{time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), time.Date(2024, 1, 2, 4, 4, 5, 6, time.FixedZone("CET", 3600))},
+	{NewValue(map[string]interface{}{"a": []int{1}}), map[string][]int64{"a": {1}}},
+	{tCanonicalConfig{Name: "a", secret: "x"}, tCanonicalConfig{Name: "a", secret: "y"}},
+}
+
+var testsCanonicalDifferent = []struct {
+	a, b interface{}
+}{
+	{1, "1"},
+	{1, 1.5},
+	{1, true},
+	{nil, 0},
+	{"a", []byte("a")},
+	{[]int{1, 2}, []int{2, 1}},
+	{map[string]int{"a": 1}, map[string]int{"a": 1, "b": 0}},
+	{map[interface{}]int{1: 1}, map[string]int{"1": 1}},
+	{time.Unix(0, 1), time.Unix(0, 2)},
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	Convey("Serialize normalized value", t, func() {
+		res, err := MarshalCanonical(map[string]interface{}{"b": 1.0, "a": []uint{2}})
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(res), ShouldEqual, "a261618102616201")
+	})
+	Convey("Serialize equal values equally", t, func() {
+		for _, test := range testsCanonicalEqual {
+			a, err := MarshalCanonical(test.a)
+			So(err, ShouldBeNil)
+			b, err := MarshalCanonical(test.b)
+			So(err, ShouldBeNil)
+			So(hex.EncodeToString(a), ShouldEqual, hex.EncodeToString(b))
+		}
+	})
+	Convey("Serialize different values differently", t, func() {
+		for _, test := range testsCanonicalDifferent {
+			a, err := MarshalCanonical(test.a)
+			So(err, ShouldBeNil)
+			b, err := MarshalCanonical(test.b)
+			So(err, ShouldBeNil)
+			So(hex.EncodeToString(a), ShouldNotEqual, hex.EncodeToString(b))
+		}
+	})
+	Convey("Fail on invalid values", t, func() {
+		node := &tCanonicalNode{}
+		node.Next = node
+		_, err := MarshalCanonical(node)
+		So(err, ShouldNotBeNil)
+		_, err = MarshalCanonical(map[string]interface{}{"f": func() {}})
+		So(err, ShouldNotBeNil)
+		_, err = MarshalCanonical(map[interface{}]int{1: 1, 1.0: 2})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Serialize shared pointers", t, func() {
+		s := &tCanonicalServer{Host: "x"}
+		_, err := MarshalCanonical(&tCanonicalConfig{Servers: []*tCanonicalServer{s, s}})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestHash(t *testing.T) {
+	Convey("Hash equal config trees equally", t, func() {
+		a := &tCanonicalConfig{Name: "svc", Servers: []*tCanonicalServer{{Host: "a", Port: 80, Tags: []string{"x"}}}}
+		b := map[string]interface{}{
+			"Servers": []interface{}{map[string]interface{}{"Tags": []interface{}{"x"}, "Port": json.Number("80"), "Host": "a", "Since": time.Time{}}},
+			"Name":    "svc",
+		}
+		ha, err := Hash(a, sha256.New())
+		So(err, ShouldBeNil)
+		hb, err := Hash(b, sha256.New())
+		So(err, ShouldBeNil)
+		So(ha, ShouldResemble, hb)
+		So(len(ha), ShouldEqual, sha256.Size)
+
+		a.Servers[0].Port = 81
+		hc, err := Hash(a, sha256.New())
+		So(err, ShouldBeNil)
+		So(hc, ShouldNotResemble, ha)
+	})
+	Convey("Fail on invalid values", t, func() {
+		_, err := Hash(make(chan int), sha256.New())
+		So(err, ShouldNotBeNil)
+	})
+}
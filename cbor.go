@@ -0,0 +1,703 @@
+package reflekt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// CBORTag is a CBOR data item with a tag which has no native representation
+type CBORTag struct {
+	Number  uint64
+	Content interface{}
+}
+
+const (
+	cborUint byte = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborTagTimeString = 0
+	cborTagTimeEpoch  = 1
+	cborTagBignum     = 2
+	cborTagNegBignum  = 3
+	cborIndefinite    = 31
+	cborBreak         = 0xff
+)
+
+var (
+	errCBORBreak = errors.New("Unexpected CBOR break")
+	bigOne       = big.NewInt(1)
+	bigIntType   = reflect.TypeOf(big.Int{})
+)
+
+// CBOREncoder writes values as CBOR (RFC 8949) to a stream
+type CBOREncoder struct {
+	w         io.Writer
+	canonical bool
+	seen      map[walkRef]bool
+}
+
+// NewCBOREncoder creates an encoder writing to the writer
+func NewCBOREncoder(w io.Writer) *CBOREncoder {
+	return &CBOREncoder{
+		w:    w,
+		seen: make(map[walkRef]bool),
+	}
+}
+
+// UseCanonical enables the core deterministic encoding of RFC 8949 section 4.2: floats are
+// written in their shortest lossless size and map keys are sorted by their encoded bytes
+func (this *CBOREncoder) UseCanonical(canonical bool) *CBOREncoder {
+	this.canonical = canonical
+	return this
+}
+
+// Encode writes the value. Integers, including big.Int, are written in the shortest form, as
+// bignum only if exceeding 64 bit. []byte is written as byte string, structs as maps of their
+// exported fields and times are written as epoch seconds (tag 1), or as RFC 3339 string (tag 0) if
+// they have fractional seconds. Cyclic values cannot be encoded.
+func (this *CBOREncoder) Encode(v interface{}) error {
+	buf := new(bytes.Buffer)
+	if err := this.encode(buf, reflectValue(v)); err != nil {
+		return err
+	}
+	_, err := this.w.Write(buf.Bytes())
+	return err
+}
+
+func (this *CBOREncoder) head(buf *bytes.Buffer, major byte, n uint64) {
+	b := make([]byte, 9)
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+		return
+	case n <= math.MaxUint8:
+		b[0], b[1] = major<<5|24, byte(n)
+		b = b[:2]
+	case n <= math.MaxUint16:
+		b[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		b = b[:3]
+	case n <= math.MaxUint32:
+		b[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		b = b[:5]
+	default:
+		b[0] = major<<5 | 27
+		binary.BigEndian.PutUint64(b[1:], n)
+	}
+	buf.Write(b)
+}
+
+func (this *CBOREncoder) encodeInt(buf *bytes.Buffer, i int64) {
+	if i >= 0 {
+		this.head(buf, cborUint, uint64(i))
+	} else {
+		this.head(buf, cborNegative, uint64(-1-i))
+	}
+}
+
+func (this *CBOREncoder) encodeBig(buf *bytes.Buffer, i *big.Int) {
+	if i.Sign() >= 0 {
+		if i.IsUint64() {
+			this.head(buf, cborUint, i.Uint64())
+			return
+		}
+		this.head(buf, cborTag, cborTagBignum)
+		this.head(buf, cborBytes, uint64(len(i.Bytes())))
+		buf.Write(i.Bytes())
+		return
+	}
+	n := new(big.Int).Neg(i)
+	n.Sub(n, bigOne)
+	if n.IsUint64() {
+		this.head(buf, cborNegative, n.Uint64())
+		return
+	}
+	this.head(buf, cborTag, cborTagNegBignum)
+	this.head(buf, cborBytes, uint64(len(n.Bytes())))
+	buf.Write(n.Bytes())
+}
+
+// float16Bits returns the bits of the half precision float equal to f, if there is any
+func float16Bits(f float32) (uint16, bool) {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127
+	mant := b & 0x7fffff
+	switch {
+	case f == 0:
+		return sign, true
+	case exp == 128:
+		return sign | 0x7c00 | uint16(mant>>13), mant&0x1fff == 0
+	case exp >= -14 && exp <= 15:
+		return sign | uint16(exp+15)<<10 | uint16(mant>>13), mant&0x1fff == 0
+	case exp >= -24 && exp < -14:
+		full, shift := mant|1<<23, uint(-1-exp)
+		return sign | uint16(full>>shift), full&(1<<shift-1) == 0
+	}
+	return 0, false
+}
+
+// float16Value returns the value of the half precision float bits
+func float16Value(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+func (this *CBOREncoder) encodeFloat(buf *bytes.Buffer, f float64, size int) {
+	if this.canonical {
+		if math.IsNaN(f) {
+			buf.Write([]byte{0xf9, 0x7e, 0x00})
+			return
+		} else if float64(float32(f)) != f {
+			size = 64
+		} else if h, ok := float16Bits(float32(f)); ok {
+			buf.Write([]byte{0xf9, byte(h >> 8), byte(h)})
+			return
+		} else {
+			size = 32
+		}
+	}
+	if size == 32 {
+		b := []byte{0xfa, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], math.Float32bits(float32(f)))
+		buf.Write(b)
+		return
+	}
+	b := []byte{0xfb, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	buf.Write(b)
+}
+
+func (this *CBOREncoder) encodeTime(buf *bytes.Buffer, t time.Time) {
+	if t.Nanosecond() == 0 {
+		this.head(buf, cborTag, cborTagTimeEpoch)
+		this.encodeInt(buf, t.Unix())
+		return
+	}
+	s := t.Format(time.RFC3339Nano)
+	this.head(buf, cborTag, cborTagTimeString)
+	this.head(buf, cborText, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func (this *CBOREncoder) encodeMap(buf *bytes.Buffer, r reflect.Value) error {
+	ref, err := this.enter(r)
+	if err != nil {
+		return err
+	}
+	defer delete(this.seen, ref)
+	keys := r.MapKeys()
+	this.head(buf, cborMap, uint64(len(keys)))
+	if !this.canonical {
+		sort.Slice(keys, func(i, j int) bool {
+			return Compare(keys[i], keys[j]) < 0
+		})
+		for _, key := range keys {
+			if err := this.encode(buf, key); err != nil {
+				return err
+			} else if err := this.encode(buf, r.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	encoded := make([][]byte, len(keys))
+	order := make([]int, len(keys))
+	for i, key := range keys {
+		kb := new(bytes.Buffer)
+		if err := this.encode(kb, key); err != nil {
+			return err
+		}
+		encoded[i], order[i] = kb.Bytes(), i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return bytes.Compare(encoded[order[i]], encoded[order[j]]) < 0
+	})
+	for _, i := range order {
+		buf.Write(encoded[i])
+		if err := this.encode(buf, r.MapIndex(keys[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enter marks the pointer, map or slice as on the current path, failing if it already is
+func (this *CBOREncoder) enter(r reflect.Value) (walkRef, error) {
+	ref := walkRef{r.Pointer(), 0, r.Type()}
+	if r.Kind() == reflect.Slice {
+		ref.l = r.Len()
+	}
+	if this.seen[ref] {
+		return ref, fmt.Errorf("Cannot encode cyclic %s as CBOR", r.Type())
+	}
+	this.seen[ref] = true
+	return ref, nil
+}
+
+func (this *CBOREncoder) encode(buf *bytes.Buffer, r reflect.Value) error {
+	for r.Kind() == reflect.Ptr || r.Kind() == reflect.Interface {
+		if r.IsNil() {
+			return buf.WriteByte(0xf6)
+		} else if r.Kind() == reflect.Ptr {
+			ref, err := this.enter(r)
+			if err != nil {
+				return err
+			}
+			defer delete(this.seen, ref)
+		}
+		r = r.Elem()
+	}
+	if !r.IsValid() {
+		return buf.WriteByte(0xf6)
+	} else if !r.CanInterface() {
+		return fmt.Errorf("Cannot encode unexported %s", r.Type())
+	}
+	switch v := r.Interface().(type) {
+	case time.Time:
+		this.encodeTime(buf, v)
+		return nil
+	case big.Int:
+		this.encodeBig(buf, &v)
+		return nil
+	case Value:
+		return this.encode(buf, reflect.ValueOf(v.v))
+	case CBORTag:
+		this.head(buf, cborTag, v.Number)
+		return this.encode(buf, reflect.ValueOf(v.Content))
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			this.encodeInt(buf, i)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		this.encodeFloat(buf, f, 64)
+		return nil
+	}
+
+	k := r.Kind()
+	switch {
+	case k == reflect.Bool:
+		if r.Bool() {
+			return buf.WriteByte(0xf5)
+		}
+		return buf.WriteByte(0xf4)
+	case IsIntKind(k):
+		this.encodeInt(buf, r.Int())
+	case IsUintKind(k):
+		this.head(buf, cborUint, r.Uint())
+	case k == reflect.Float32:
+		this.encodeFloat(buf, r.Float(), 32)
+	case k == reflect.Float64:
+		this.encodeFloat(buf, r.Float(), 64)
+	case k == reflect.String:
+		this.head(buf, cborText, uint64(r.Len()))
+		buf.WriteString(r.String())
+	case isListKind(k) && r.Type().Elem().Kind() == reflect.Uint8:
+		this.head(buf, cborBytes, uint64(r.Len()))
+		for i := 0; i < r.Len(); i++ {
+			buf.WriteByte(byte(r.Index(i).Uint()))
+		}
+	case isListKind(k):
+		if k == reflect.Slice {
+			ref, err := this.enter(r)
+			if err != nil {
+				return err
+			}
+			defer delete(this.seen, ref)
+		}
+		this.head(buf, cborArray, uint64(r.Len()))
+		for i := 0; i < r.Len(); i++ {
+			if err := this.encode(buf, r.Index(i)); err != nil {
+				return err
+			}
+		}
+	case k == reflect.Map:
+		return this.encodeMap(buf, r)
+	case k == reflect.Struct:
+		return this.encode(buf, reflect.ValueOf(structFields(r)))
+	default:
+		return fmt.Errorf("Cannot encode %s as CBOR", r.Type())
+	}
+	return nil
+}
+
+// CBORDecoder reads CBOR values from a stream
+type CBORDecoder struct {
+	r      *bufio.Reader
+	filler *StructFiller
+	depth  int
+}
+
+// NewCBORDecoder creates a decoder reading from the reader
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	return &CBORDecoder{
+		r:      bufio.NewReader(r),
+		filler: NewStructFiller(),
+	}
+}
+
+// UseFiller sets the StructFiller used to decode into structs
+func (this *CBORDecoder) UseFiller(filler *StructFiller) *CBORDecoder {
+	this.filler = filler
+	return this
+}
+
+// Decode reads the next value into the pointer, which can point to a Value, an interface{}, a
+// struct, which is filled with the StructFiller of the decoder, or any other type the decoded
+// value can be cast to. Integers are decoded as int64, or uint64 if too large, bignums as
+// *big.Int, floats as float64, byte strings as []byte, maps with only text keys as
+// map[string]interface{} and other maps as map[interface{}]interface{}, times as time.Time and
+// other tags as CBORTag. Indefinite-length strings, arrays and maps are supported, nested at most
+// 10000 levels deep. Returns io.EOF if the stream is exhausted.
+func (this *CBORDecoder) Decode(dst interface{}) error {
+	c, err := this.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	v, err := this.decodeItem(c)
+	if err != nil {
+		return this.unexpected(err)
+	}
+	return this.filler.decodeInto(v, dst)
+}
+
+// unexpected converts io.EOF within a value into io.ErrUnexpectedEOF
+func (this *CBORDecoder) unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (this *CBORDecoder) read(n uint64) ([]byte, error) {
+	if n <= 1<<16 {
+		b := make([]byte, n)
+		_, err := io.ReadFull(this.r, b)
+		return b, this.unexpected(err)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(this.r, int64(n)))
+	if err == nil && uint64(len(b)) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+// argument reads the argument of the initial byte and whether the length is indefinite
+func (this *CBORDecoder) argument(c byte) (uint64, bool, error) {
+	info := c & 0x1f
+	switch {
+	case info < 24:
+		return uint64(info), false, nil
+	case info == cborIndefinite:
+		return 0, true, nil
+	case info > 27:
+		return 0, false, fmt.Errorf("Invalid CBOR additional information %d", info)
+	}
+	b, err := this.read(1 << (info - 24))
+	if err != nil {
+		return 0, false, err
+	}
+	switch len(b) {
+	case 1:
+		return uint64(b[0]), false, nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), false, nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), false, nil
+	}
+	return binary.BigEndian.Uint64(b), false, nil
+}
+
+func (this *CBORDecoder) decode() (interface{}, error) {
+	c, err := this.r.ReadByte()
+	if err != nil {
+		return nil, this.unexpected(err)
+	}
+	return this.decodeItem(c)
+}
+
+func (this *CBORDecoder) decodeItem(c byte) (interface{}, error) {
+	major := c >> 5
+	if major == cborSimple {
+		return this.decodeSimple(c)
+	}
+	n, indefinite, err := this.argument(c)
+	if err != nil {
+		return nil, err
+	} else if indefinite && major != cborBytes && major != cborText && major != cborArray && major != cborMap {
+		return nil, fmt.Errorf("Invalid indefinite length for CBOR major type %d", major)
+	}
+
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			i := new(big.Int).SetUint64(n)
+			return i.Neg(i).Sub(i, bigOne), nil
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		b, err := this.decodeString(major, n, indefinite)
+		if major == cborText {
+			return string(b), err
+		}
+		return b, err
+	case cborArray:
+		return this.decodeArray(n, indefinite)
+	case cborMap:
+		return this.decodeMap(n, indefinite)
+	}
+	return this.decodeTag(n)
+}
+
+func (this *CBORDecoder) decodeSimple(c byte) (interface{}, error) {
+	switch c & 0x1f {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := this.read(2)
+		if err != nil {
+			return nil, err
+		}
+		return float16Value(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := this.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := this.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case cborIndefinite:
+		return nil, errCBORBreak
+	}
+	return nil, fmt.Errorf("Unsupported CBOR simple value 0x%02x", c)
+}
+
+// decodeString reads a definite string or the chunks of an indefinite string
+func (this *CBORDecoder) decodeString(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		return this.read(n)
+	}
+	res := []byte{}
+	for {
+		c, err := this.r.ReadByte()
+		if err != nil {
+			return nil, this.unexpected(err)
+		} else if c == cborBreak {
+			return res, nil
+		} else if c>>5 != major || c&0x1f == cborIndefinite {
+			return nil, fmt.Errorf("Invalid chunk in indefinite CBOR string")
+		}
+		n, _, err := this.argument(c)
+		if err != nil {
+			return nil, err
+		}
+		b, err := this.read(n)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, b...)
+	}
+}
+
+// nest enters an array, map or tag, failing beyond maxDecodeDepth
+func (this *CBORDecoder) nest() error {
+	if this.depth >= maxDecodeDepth {
+		return fmt.Errorf("CBOR data exceeds maximum nesting depth of %d", maxDecodeDepth)
+	}
+	this.depth++
+	return nil
+}
+
+// next reads the next item of an array or map, returning false at the end
+func (this *CBORDecoder) next(i, n uint64, indefinite bool) (interface{}, bool, error) {
+	if !indefinite && i >= n {
+		return nil, false, nil
+	}
+	v, err := this.decode()
+	if err == errCBORBreak && indefinite {
+		return nil, false, nil
+	}
+	return v, err == nil, err
+}
+
+func (this *CBORDecoder) decodeArray(n uint64, indefinite bool) (interface{}, error) {
+	if err := this.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { this.depth-- }()
+	res := make([]interface{}, 0, minUint(n, 1024))
+	for i := uint64(0); ; i++ {
+		v, ok, err := this.next(i, n, indefinite)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			return res, nil
+		}
+		res = append(res, v)
+	}
+}
+
+func (this *CBORDecoder) decodeMap(n uint64, indefinite bool) (interface{}, error) {
+	if err := this.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { this.depth-- }()
+	res := make(map[interface{}]interface{}, minUint(n, 1024))
+	strs := true
+	for i := uint64(0); ; i++ {
+		k, ok, err := this.next(i, n, indefinite)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+		v, err := this.decode()
+		if err != nil {
+			return nil, err
+		}
+		if kr := reflect.ValueOf(k); kr.IsValid() && !kr.Type().Comparable() {
+			return nil, fmt.Errorf("Unsupported CBOR map key of type %T", k)
+		}
+		_, ok = k.(string)
+		strs = strs && ok
+		res[k] = v
+	}
+	if !strs {
+		return res, nil
+	}
+	m := make(map[string]interface{}, len(res))
+	for k, v := range res {
+		m[k.(string)] = v
+	}
+	return m, nil
+}
+
+func (this *CBORDecoder) decodeTag(n uint64) (interface{}, error) {
+	if err := this.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { this.depth-- }()
+	v, err := this.decode()
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case cborTagTimeString:
+		if s, ok := v.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	case cborTagTimeEpoch:
+		switch x := v.(type) {
+		case int64:
+			return time.Unix(x, 0).UTC(), nil
+		case float64:
+			sec, frac := math.Modf(x)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+		}
+	case cborTagBignum, cborTagNegBignum:
+		if b, ok := v.([]byte); ok {
+			i := new(big.Int).SetBytes(b)
+			if n == cborTagNegBignum {
+				i.Neg(i).Sub(i, bigOne)
+			}
+			return i, nil
+		}
+	default:
+		return CBORTag{n, v}, nil
+	}
+	return nil, fmt.Errorf("Invalid content %T for CBOR tag %d", v, n)
+}
+
+// MarshalCBOR encodes the value as CBOR. See CBOREncoder.Encode.
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := NewCBOREncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalCanonicalCBOR encodes the value as deterministic CBOR. See CBOREncoder.UseCanonical.
+func MarshalCanonicalCBOR(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := NewCBOREncoder(buf).UseCanonical(true).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCBOR decodes CBOR into the pointer. See CBORDecoder.Decode.
+func UnmarshalCBOR(data []byte, dst interface{}) error {
+	d := NewCBORDecoder(bytes.NewReader(data))
+	if err := d.Decode(dst); err != nil {
+		return d.unexpected(err)
+	} else if _, err := d.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("Unexpected data after CBOR value")
+	}
+	return nil
+}
+
+// MarshalCBOR encodes the wrapped value as CBOR
+func (this *Value) MarshalCBOR() ([]byte, error) {
+	return MarshalCBOR(this.v)
+}
+
+// UnmarshalCBOR decodes CBOR into the value
+func (this *Value) UnmarshalCBOR(data []byte) error {
+	return UnmarshalCBOR(data, this)
+}
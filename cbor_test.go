@@ -0,0 +1,229 @@
+package reflekt
+
+import (
+	"bytes"
+	"encoding/hex"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type tCBORReading struct {
+	Device string
+	Temp   float64
+	At     time.Time
+	Raw    []byte
+}
+
+type tCBORBigFields struct {
+	A big.Int
+	B *big.Int
+	C *big.Int
+	D big.Int
+}
+
+type tCBORNode struct {
+	Next *tCBORNode
+}
+
+func tCBORBig(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 10)
+	return i
+}
+
+var testsCBOR = []struct {
+	value     interface{}
+	canonical bool
+	hex       string
+}{
+	{0, false, "00"},
+	{23, false, "17"},
+	{24, false, "1818"},
+	{1000, false, "1903e8"},
+	{uint32(1000000), false, "1a000f4240"},
+	{uint64(18446744073709551615), false, "1bffffffffffffffff"},
+	{-1, false, "20"},
+	{-1000, false, "3903e7"},
+	{tCBORBig("18446744073709551616"), false, "c249010000000000000000"},
+	{tCBORBig("-18446744073709551617"), false, "c349010000000000000000"},
+	{1.1, false, "fb3ff199999999999a"},
+	{float32(100000), false, "fa47c35000"},
+	{0.0, true, "f90000"},
+	{1.5, true, "f93e00"},
+	{-4.0, true, "f9c400"},
+	{65504.0, true, "f97bff"},
+	{5.960464477539063e-8, true, "f90001"},
+	{100000.0, true, "fa47c35000"},
+	{3.4028234663852886e+38, true, "fa7f7fffff"},
+	{1.1, true, "fb3ff199999999999a"},
+	{math.Inf(1), true, "f97c00"},
+	{math.NaN(), true, "f97e00"},
+	{false, false, "f4"},
+	{true, false, "f5"},
+	{nil, false, "f6"},
+	{"a", false, "6161"},
+	{"ü", false, "62c3bc"},
+	{[]byte{1, 2, 3, 4}, false, "4401020304"},
+	{[]interface{}{1, []int{2, 3}, []int{4, 5}}, false, "8301820203820405"},
+	{map[string]interface{}{"a": 1, "b": []int{2, 3}}, false, "a26161016162820203"},
+	{map[interface{}]interface{}{10: 1, 100: 2, -1: 3, "z": 4, "aa": 5}, true, "a50a011864022003617a0462616105"},
+	{map[interface{}]interface{}{10: 1, 100: 2, -1: 3, "z": 4, "aa": 5}, false, "a520030a0118640262616105617a04"},
+	{time.Unix(1363896240, 0), false, "c11a514b67b0"},
+	{CBORTag{32, "http://www.example.com"}, false, "d82076687474703a2f2f7777772e6578616d706c652e636f6d"},
+}
+
+func TestMarshalCBOR(t *testing.T) {
+	Convey("Encode values", t, func() {
+		for _, test := range testsCBOR {
+			buf := new(bytes.Buffer)
+			So(NewCBOREncoder(buf).UseCanonical(test.canonical).Encode(test.value), ShouldBeNil)
+			So(hex.EncodeToString(buf.Bytes()), ShouldEqual, test.hex)
+		}
+	})
+	Convey("Encode canonical", t, func() {
+		res, err := MarshalCanonicalCBOR(map[string]float64{"b": 1, "a": 0.5})
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(res), ShouldEqual, "a26161f938006162f93c00")
+	})
+	Convey("Encode time with fractional seconds", t, func() {
+		res, err := MarshalCBOR(time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC))
+		So(err, ShouldBeNil)
+		So(string(res[2:]), ShouldEqual, "2013-03-21T20:04:00.5Z")
+	})
+	Convey("Fail on unsupported values", t, func() {
+		_, err := MarshalCBOR([]interface{}{make(chan int)})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Fail on cyclic values", t, func() {
+		m := map[string]interface{}{}
+		m["self"] = m
+		_, err := MarshalCBOR(m)
+		So(err, ShouldNotBeNil)
+		_, err = MarshalCanonicalCBOR(m)
+		So(err, ShouldNotBeNil)
+		s := []interface{}{nil}
+		s[0] = s
+		_, err = MarshalCBOR(s)
+		So(err, ShouldNotBeNil)
+		node := &tCBORNode{}
+		node.Next = node
+		_, err = MarshalCBOR(node)
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Encode shared values", t, func() {
+		node := &tCBORNode{}
+		res, err := MarshalCBOR([]interface{}{node, node})
+		So(err, ShouldBeNil)
+		So(hex.EncodeToString(res), ShouldEqual, "82a1644e657874f6a1644e657874f6")
+	})
+}
+
+var testsUnmarshalCBOR = []struct {
+	hex    string
+	expect interface{}
+}{
+	{"1bffffffffffffffff", uint64(18446744073709551615)},
+	{"3bffffffffffffffff", tCBORBig("-18446744073709551616")},
+	{"c249010000000000000000", tCBORBig("18446744073709551616")},
+	{"3863", int64(-100)},
+	{"f93e00", 1.5},
+	{"f90001", 5.960464477539063e-8},
+	{"fa47c35000", 100000.0},
+	{"f7", nil},
+	{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+	{"c1fb41d452d9ec200000", time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC)},
+	{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+	{"7f657374726561646d696e67ff", "streaming"},
+	{"9fff", []interface{}{}},
+	{"9f018202039f0405ffff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+	{"bf61610161629f0203ffff", map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+	{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+	{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", CBORTag{32, "http://www.example.com"}},
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	Convey("Decode values", t, func() {
+		for _, test := range testsUnmarshalCBOR {
+			data, _ := hex.DecodeString(test.hex)
+			var v interface{}
+			So(UnmarshalCBOR(data, &v), ShouldBeNil)
+			So(v, ShouldResemble, test.expect)
+		}
+	})
+	Convey("Decode into Value", t, func() {
+		data, _ := hex.DecodeString("bf6174182a6269640c61629f0203ff616ec249010000000000000000ff")
+		v := NewValue(nil)
+		So(v.UnmarshalCBOR(data), ShouldBeNil)
+		So(v.Get("t").Int(), ShouldEqual, 42)
+		So(v.Get("b").Ints(), ShouldResemble, []int{2, 3})
+		So(v.Get("n").String(), ShouldEqual, "18446744073709551616")
+		So(v.StringMap()["id"], ShouldEqual, "12")
+	})
+	Convey("Decode into struct", t, func() {
+		from := tCBORReading{Device: "d1", Temp: 21.5, At: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Raw: []byte{1}}
+		data, err := MarshalCanonicalCBOR(from)
+		So(err, ShouldBeNil)
+		to := tCBORReading{}
+		So(UnmarshalCBOR(data, &to), ShouldBeNil)
+		So(to, ShouldResemble, from)
+	})
+	Convey("Decode into big.Int", t, func() {
+		data, _ := hex.DecodeString("c249010000000000000000")
+		i := new(big.Int)
+		So(UnmarshalCBOR(data, i), ShouldBeNil)
+		So(i.String(), ShouldEqual, "18446744073709551616")
+		So(UnmarshalCBOR([]byte{0x20}, i), ShouldBeNil)
+		So(i.Int64(), ShouldEqual, -1)
+
+		data, err := MarshalCBOR(map[string]interface{}{"A": tCBORBig("-18446744073709551617"), "B": -5, "C": "123", "D": uint64(18446744073709551615)})
+		So(err, ShouldBeNil)
+		to := tCBORBigFields{}
+		So(UnmarshalCBOR(data, &to), ShouldBeNil)
+		So(to.A.String(), ShouldEqual, "-18446744073709551617")
+		So(to.B.String(), ShouldEqual, "-5")
+		So(to.C.String(), ShouldEqual, "123")
+		So(to.D.String(), ShouldEqual, "18446744073709551615")
+
+		data, _ = MarshalCBOR(map[string]interface{}{"B": 1.5})
+		So(UnmarshalCBOR(data, &to), ShouldNotBeNil)
+	})
+	Convey("Fail on invalid data", t, func() {
+		var v interface{}
+		for _, h := range []string{"", "18", "62c3", "9f01", "ff", "f0", "1c", "5f6100ff", "c16161", "a1820102f6", "0001"} {
+			data, _ := hex.DecodeString(h)
+			So(UnmarshalCBOR(data, &v), ShouldNotBeNil)
+		}
+	})
+	Convey("Fail on too deeply nested data", t, func() {
+		var v interface{}
+		So(UnmarshalCBOR(append(bytes.Repeat([]byte{0x81}, maxDecodeDepth), 0x01), &v), ShouldBeNil)
+		So(UnmarshalCBOR(append(bytes.Repeat([]byte{0x81}, maxDecodeDepth+1), 0x01), &v), ShouldNotBeNil)
+		So(UnmarshalCBOR(bytes.Repeat([]byte{0xc6}, 1000000), &v), ShouldNotBeNil)
+		So(UnmarshalCBOR(bytes.Repeat([]byte{0x9f}, 1000000), &v), ShouldNotBeNil)
+	})
+}
+
+func TestCBORStream(t *testing.T) {
+	Convey("Encode and decode multiple values", t, func() {
+		buf := new(bytes.Buffer)
+		enc := NewCBOREncoder(buf)
+		So(enc.Encode(tCBORReading{Device: "a"}), ShouldBeNil)
+		So(enc.Encode(tCBORReading{Device: "b"}), ShouldBeNil)
+
+		dec := NewCBORDecoder(buf)
+		devices := []string{}
+		for {
+			r := tCBORReading{}
+			err := dec.Decode(&r)
+			if err == io.EOF {
+				break
+			}
+			So(err, ShouldBeNil)
+			devices = append(devices, r.Device)
+		}
+		So(devices, ShouldResemble, []string{"a", "b"})
+	})
+}
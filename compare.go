@@ -0,0 +1,276 @@
+package reflekt
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ranks of kinds in the order of Compare
+const (
+	rankNil = iota
+	rankBool
+	rankNumber
+	rankString
+	rankTime
+	rankList
+	rankEntries
+	rankOther
+)
+
+func compareRank(r reflect.Value) int {
+	k := r.Kind()
+	switch {
+	case !r.IsValid() || isNilCollection(r):
+		return rankNil
+	case k == reflect.Bool:
+		return rankBool
+	case IsNumericKind(k):
+		return rankNumber
+	case k == reflect.String:
+		if _, ok := scalarNumber(r); ok {
+			return rankNumber
+		}
+		return rankString
+	case r.Type() == timeType:
+		return rankTime
+	case isListKind(k):
+		return rankList
+	case k == reflect.Map || k == reflect.Struct:
+		return rankEntries
+	default:
+		return rankOther
+	}
+}
+
+type orderer struct {
+	seen map[[2]walkRef]bool
+}
+
+// Compare returns -1 if a is less than b, 1 if a is greater than b and 0 if both are equal.
+// It defines a total order over all kinds, from lowest to highest: nil, bools, numbers (including
+// strings representing numbers, compared numerically), other strings, times, slices and arrays
+// (compared element-wise) and finally maps and structs (compared by sorted keys, then values).
+func Compare(a, b interface{}) int {
+	o := &orderer{
+		seen: make(map[[2]walkRef]bool),
+	}
+	return o.compare(reflectValue(a), reflectValue(b))
+}
+
+func compareInts(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case math.IsNaN(a) && math.IsNaN(b):
+		return 0
+	case math.IsNaN(a):
+		return -1
+	case math.IsNaN(b):
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareNumbers compares integers exactly with each other and with floats
+func compareNumbers(a, b reflect.Value) int {
+	ia, intA := scalarInteger(a)
+	ib, intB := scalarInteger(b)
+	if intA && intB {
+		return ia.Cmp(ib)
+	}
+	fa, _ := scalarNumber(a)
+	fb, _ := scalarNumber(b)
+	if intA && !math.IsNaN(fb) {
+		return new(big.Float).SetInt(ia).Cmp(big.NewFloat(fb))
+	} else if intB && !math.IsNaN(fa) {
+		return big.NewFloat(fa).Cmp(new(big.Float).SetInt(ib))
+	}
+	return compareFloats(fa, fb)
+}
+
+// enter marks a pair of pointers, maps or slices as being compared, returning false if it already is
+func (this *orderer) enter(a, b reflect.Value) ([2]walkRef, bool) {
+	ref := [2]walkRef{{a.Pointer(), 0, a.Type()}, {b.Pointer(), 0, b.Type()}}
+	if a.Kind() == reflect.Slice {
+		ref[0].l, ref[1].l = a.Len(), b.Len()
+	}
+	if this.seen[ref] {
+		return ref, false
+	}
+	this.seen[ref] = true
+	return ref, true
+}
+
+func (this *orderer) compare(a, b reflect.Value) int {
+	if a.Kind() == reflect.Ptr && b.Kind() == reflect.Ptr && !a.IsNil() && !b.IsNil() {
+		ref, ok := this.enter(a, b)
+		if !ok {
+			return 0
+		}
+		defer delete(this.seen, ref)
+	}
+	a, b = indirect(a), indirect(b)
+	ra, rb := compareRank(a), compareRank(b)
+	if ra != rb {
+		return compareInts(int64(ra), int64(rb))
+	}
+
+	switch ra {
+	case rankBool:
+		return compareInts(int64(AsInt(a)), int64(AsInt(b)))
+	case rankNumber:
+		return compareNumbers(a, b)
+	case rankString:
+		return strings.Compare(a.String(), b.String())
+	case rankTime:
+		if a.CanInterface() && b.CanInterface() {
+			ta, tb := a.Interface().(time.Time), b.Interface().(time.Time)
+			return compareInts(ta.UnixNano(), tb.UnixNano())
+		}
+	case rankList:
+		if a.Kind() == reflect.Slice && b.Kind() == reflect.Slice {
+			ref, ok := this.enter(a, b)
+			if !ok {
+				return 0
+			}
+			defer delete(this.seen, ref)
+		}
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			if c := this.compare(a.Index(i), b.Index(i)); c != 0 {
+				return c
+			}
+		}
+		return compareInts(int64(a.Len()), int64(b.Len()))
+	case rankEntries:
+		if a.Kind() == reflect.Map && b.Kind() == reflect.Map {
+			ref, ok := this.enter(a, b)
+			if !ok {
+				return 0
+			}
+			defer delete(this.seen, ref)
+		}
+		return this.compareEntries(a, b)
+	case rankOther:
+		return strings.Compare(a.Type().String(), b.Type().String())
+	}
+	return 0
+}
+
+func (this *orderer) compareEntries(a, b reflect.Value) int {
+	ae, be := valueEntries(a, false, nil), valueEntries(b, false, nil)
+	keys := func(e map[string]reflect.Value) []string {
+		res := make([]string, 0, len(e))
+		for k := range e {
+			res = append(res, k)
+		}
+		sort.Strings(res)
+		return res
+	}
+	ak, bk := keys(ae), keys(be)
+	for i := 0; i < len(ak) && i < len(bk); i++ {
+		if c := strings.Compare(ak[i], bk[i]); c != 0 {
+			return c
+		}
+	}
+	if c := compareInts(int64(len(ak)), int64(len(bk))); c != 0 {
+		return c
+	}
+	for _, k := range ak {
+		if c := this.compare(ae[k], be[k]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// pathValue returns the value at the path in maps, structs, slices and arrays. Struct fields can
+// also be addressed by their snake case or lower case name. Returns an invalid value if not found.
+func pathValue(r reflect.Value, path []string) reflect.Value {
+	for _, seg := range path {
+		r = indirect(r)
+		switch r.Kind() {
+		case reflect.Map, reflect.Struct:
+			entries := valueEntries(r, false, nil)
+			v, ok := entries[seg]
+			if !ok && r.Kind() == reflect.Struct {
+				for n, e := range entries {
+					if snakeCase(n) == seg || strings.ToLower(n) == seg {
+						v, ok = e, true
+						break
+					}
+				}
+			}
+			if !ok {
+				return reflect.Value{}
+			}
+			r = v
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= r.Len() {
+				return reflect.Value{}
+			}
+			r = r.Index(i)
+		default:
+			return reflect.Value{}
+		}
+	}
+	return r
+}
+
+// SortBy sorts a slice of maps or structs in place, stable and by the values at the given dotted
+// paths, using the order of Compare. Paths prefixed with `-` sort descending. Subsequent paths are
+// only used if the values of all previous paths are equal.
+func SortBy(slice interface{}, paths ...string) error {
+	r := indirect(reflectValue(slice))
+	if r.Kind() != reflect.Slice {
+		return fmt.Errorf("Expected slice, got %s", r.Kind())
+	}
+	type sortPath struct {
+		path []string
+		desc bool
+	}
+	sorts := make([]sortPath, len(paths))
+	for i, p := range paths {
+		desc := strings.HasPrefix(p, "-")
+		p = strings.TrimPrefix(p, "-")
+		if p == "" {
+			sorts[i] = sortPath{[]string{}, desc}
+		} else {
+			sorts[i] = sortPath{strings.Split(p, "."), desc}
+		}
+	}
+	o := &orderer{
+		seen: make(map[[2]walkRef]bool),
+	}
+	sort.SliceStable(r.Interface(), func(i, j int) bool {
+		a, b := r.Index(i), r.Index(j)
+		for _, s := range sorts {
+			c := o.compare(pathValue(a, s.path), pathValue(b, s.path))
+			if s.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
@@ -0,0 +1,123 @@
+package reflekt
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"math"
+	"testing"
+	"time"
+)
+
+var testsCompare = []struct {
+	a   interface{}
+	b   interface{}
+	res int
+}{
+	{a: nil, b: nil, res: 0},
+	{a: nil, b: false, res: -1},
+	{a: []int(nil), b: false, res: -1},
+	{a: false, b: true, res: -1},
+	{a: true, b: 0, res: -1},
+	{a: 1, b: 2, res: -1},
+	{a: 2, b: 1.5, res: 1},
+	{a: "10", b: 9, res: 1},
+	{a: "10", b: "9", res: 1},
+	{a: "1.0", b: 1, res: 0},
+	{a: 1e10, b: "foo", res: -1},
+	{a: uint64(math.MaxUint64), b: uint64(math.MaxUint64 - 1), res: 1},
+	{a: int64(-1), b: uint64(math.MaxUint64), res: -1},
+	{a: int64(1<<53 + 1), b: uint64(1 << 53), res: 1},
+	{a: int64(1<<53 + 1), b: float64(1 << 53), res: 1},
+	{a: "9007199254740993", b: 9007199254740992.0, res: 1},
+	{a: uint64(math.MaxUint64), b: float64(math.MaxUint64), res: -1},
+	{a: 1, b: 1.0, res: 0},
+	{a: 1, b: math.Inf(-1), res: 1},
+	{a: 1, b: math.NaN(), res: 1},
+	{a: "18446744073709551615", b: uint64(math.MaxUint64 - 1), res: 1},
+	{a: "nan", b: "abc", res: 1},
+	{a: "Inf", b: 1, res: 1},
+	{a: "infinity", b: "j", res: -1},
+	{a: "bar", b: "foo", res: -1},
+	{a: "foo", b: time.Now(), res: -1},
+	{a: time.Unix(2, 0), b: time.Unix(1, 0), res: 1},
+	{a: time.Now(), b: []int{}, res: -1},
+	{a: []int{1, 2}, b: []int{1, 3}, res: -1},
+	{a: []int{1, 2}, b: []int{1}, res: 1},
+	{a: []int{1, 2}, b: [2]string{"1", "2"}, res: 0},
+	{a: []int{1}, b: map[string]int{}, res: -1},
+	{a: map[string]int{"a": 1}, b: map[string]int{"b": 0}, res: -1},
+	{a: map[string]int{"a": 2}, b: map[string]int{"a": 1}, res: 1},
+	{a: map[string]int{"a": 1}, b: map[string]int{"a": 1, "b": 1}, res: -1},
+	{a: struct{ A int }{1}, b: map[string]interface{}{"A": "1"}, res: 0},
+}
+
+func TestCompare(t *testing.T) {
+	Convey("Compare values", t, func() {
+		for i, test := range testsCompare {
+			Convey(fmt.Sprintf("%d) %s (%v) vs %s (%v) -> %d", i, typeName(test.a), test.a, typeName(test.b), test.b, test.res), func() {
+				So(Compare(test.a, test.b), ShouldEqual, test.res)
+				So(Compare(test.b, test.a), ShouldEqual, -test.res)
+			})
+		}
+	})
+	Convey("Compare cyclic values", t, func() {
+		m := map[string]interface{}{"a": 1}
+		m["self"] = m
+		So(Compare(m, m), ShouldEqual, 0)
+		s := []interface{}{1, nil}
+		s[1] = s
+		So(Compare(s, s), ShouldEqual, 0)
+		l := []map[string]interface{}{{"n": "a", "m": m}, {"n": "b", "m": map[string]interface{}{"a": 0}}}
+		So(SortBy(l, "m"), ShouldBeNil)
+		So(AsStrings(pluck(l, "n")), ShouldResemble, []string{"b", "a"})
+	})
+}
+
+type tSort struct {
+	Name string
+	Age  int
+	Sub  struct {
+		Rank interface{}
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	Convey("Sort slices of maps", t, func() {
+		s := []map[string]interface{}{
+			{"name": "c", "age": "10"},
+			{"name": "a", "age": 9},
+			{"name": "b", "age": 10.0},
+			{"name": "d"},
+		}
+		So(SortBy(s, "age"), ShouldBeNil)
+		So(AsStrings(pluck(s, "name")), ShouldResemble, []string{"d", "a", "c", "b"})
+		So(SortBy(s, "-age", "name"), ShouldBeNil)
+		So(AsStrings(pluck(s, "name")), ShouldResemble, []string{"b", "c", "a", "d"})
+	})
+	Convey("Sort slices of structs by nested paths", t, func() {
+		s := []*tSort{{Name: "a", Age: 2}, {Name: "b", Age: 1}, {Name: "c", Age: 2}}
+		s[0].Sub.Rank = "2"
+		s[1].Sub.Rank = 1
+		s[2].Sub.Rank = 1.5
+		So(SortBy(&s, "sub.rank"), ShouldBeNil)
+		So([]string{s[0].Name, s[1].Name, s[2].Name}, ShouldResemble, []string{"b", "c", "a"})
+		So(SortBy(s, "-Age"), ShouldBeNil)
+		So([]string{s[0].Name, s[1].Name, s[2].Name}, ShouldResemble, []string{"c", "a", "b"})
+	})
+	Convey("Sort slices of scalars", t, func() {
+		s := []interface{}{"b", 2, nil, "10", true, "a"}
+		So(SortBy(s, ""), ShouldBeNil)
+		So(s, ShouldResemble, []interface{}{nil, true, 2, "10", "a", "b"})
+	})
+	Convey("Fail on non-slices", t, func() {
+		So(SortBy(map[string]int{}, "foo"), ShouldNotBeNil)
+	})
+}
+
+func pluck(s []map[string]interface{}, key string) []interface{} {
+	res := make([]interface{}, len(s))
+	for i, m := range s {
+		res[i] = m[key]
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+package reflekt
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// Cloner can be implemented by types which need custom copying in DeepCopy
+type Cloner interface {
+	Clone() interface{}
+}
+
+var (
+	clonerType = reflect.TypeOf((*Cloner)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+type copier struct {
+	seen map[walkRef]reflect.Value
+}
+
+// DeepCopy returns a copy of v, which does not share any maps, slices or pointers with the
+// original. Pointers, maps and slices referenced multiple times are copied once, so aliasing
+// and cycles are preserved. Unexported struct fields are copied as well. Channels and functions
+// are not copied. Types implementing Cloner are copied with their Clone method.
+func DeepCopy(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(v)).Interface()
+}
+
+func deepCopy(r reflect.Value) reflect.Value {
+	c := &copier{
+		seen: make(map[walkRef]reflect.Value),
+	}
+	return c.copy(r)
+}
+
+func (this *copier) clone(r reflect.Value) (reflect.Value, bool) {
+	if r.Kind() == reflect.Interface || !r.CanInterface() || !r.Type().Implements(clonerType) {
+		return r, false
+	} else if r.Kind() == reflect.Ptr && r.IsNil() {
+		return r, false
+	}
+	c, err := assignable(reflect.ValueOf(r.Interface().(Cloner).Clone()), r.Type())
+	return c, err == nil
+}
+
+func (this *copier) copy(r reflect.Value) reflect.Value {
+	if c, ok := this.clone(r); ok {
+		return c
+	}
+	switch r.Kind() {
+	case reflect.Ptr:
+		if r.IsNil() {
+			return reflect.Zero(r.Type())
+		}
+		ref := walkRef{r.Pointer(), 0, r.Type()}
+		if c, ok := this.seen[ref]; ok {
+			return c
+		}
+		c := reflect.New(r.Type().Elem())
+		this.seen[ref] = c
+		c.Elem().Set(this.copy(r.Elem()))
+		return c
+	case reflect.Interface:
+		if r.IsNil() {
+			return reflect.Zero(r.Type())
+		}
+		c := reflect.New(r.Type()).Elem()
+		c.Set(this.copy(r.Elem()))
+		return c
+	case reflect.Map:
+		if r.IsNil() {
+			return reflect.Zero(r.Type())
+		}
+		ref := walkRef{r.Pointer(), 0, r.Type()}
+		if c, ok := this.seen[ref]; ok {
+			return c
+		}
+		c := reflect.MakeMap(r.Type())
+		this.seen[ref] = c
+		for _, k := range r.MapKeys() {
+			c.SetMapIndex(k, this.copy(r.MapIndex(k)))
+		}
+		return c
+	case reflect.Slice:
+		if r.IsNil() {
+			return reflect.Zero(r.Type())
+		}
+		ref := walkRef{r.Pointer(), r.Len(), r.Type()}
+		if c, ok := this.seen[ref]; ok {
+			return c
+		}
+		c := reflect.MakeSlice(r.Type(), r.Len(), r.Len())
+		this.seen[ref] = c
+		for i := 0; i < r.Len(); i++ {
+			c.Index(i).Set(this.copy(r.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(r.Type()).Elem()
+		for i := 0; i < r.Len(); i++ {
+			c.Index(i).Set(this.copy(r.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(r.Type()).Elem()
+		if r.Type() == timeType {
+			c.Set(r)
+			return c
+		}
+		if !r.CanAddr() {
+			a := reflect.New(r.Type()).Elem()
+			a.Set(r)
+			r = a
+		}
+		t := r.Type()
+		for i := 0; i < r.NumField(); i++ {
+			if t.Field(i).PkgPath == "" {
+				c.Field(i).Set(this.copy(r.Field(i)))
+			} else {
+				ft := t.Field(i).Type
+				src := reflect.NewAt(ft, unsafe.Pointer(r.Field(i).UnsafeAddr())).Elem()
+				dst := reflect.NewAt(ft, unsafe.Pointer(c.Field(i).UnsafeAddr())).Elem()
+				dst.Set(this.copy(src))
+			}
+		}
+		return c
+	default:
+		return r
+	}
+}
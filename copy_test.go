@@ -0,0 +1,110 @@
+package reflekt
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+type tCopy struct {
+	A    int
+	B    []string
+	C    map[string]interface{}
+	D    *tCopy
+	E    [2]*int
+	T    time.Time
+	f    []int
+	g    *tCopy
+	Func func()
+}
+
+type tCloner struct {
+	Name   string
+	Clones *int
+}
+
+func (this *tCloner) Clone() interface{} {
+	*this.Clones++
+	return &tCloner{Name: this.Name + " (clone)", Clones: this.Clones}
+}
+
+var testsDeepCopy = []interface{}{
+	nil,
+	1,
+	"foo",
+	[]int{1, 2},
+	[]interface{}{1, "2", []string{"3"}},
+	map[string]interface{}{"foo": map[string]interface{}{"bar": []interface{}{1}}},
+	[2]string{"foo", "bar"},
+	&tCopy{A: 1, B: []string{"x"}, C: map[string]interface{}{"y": 2}, T: time.Unix(1000, 0)},
+	tCopy{D: &tCopy{A: 2}, f: []int{1}, g: &tCopy{A: 3}},
+}
+
+func TestDeepCopy(t *testing.T) {
+	Convey("Copy values deeply", t, func() {
+		for i, test := range testsDeepCopy {
+			Convey(fmt.Sprintf("%d) From %s", i, typeName(test)), func() {
+				So(DeepCopy(test), ShouldResemble, test)
+			})
+		}
+	})
+}
+
+func TestDeepCopy_Sharing(t *testing.T) {
+	Convey("Do not share references", t, func() {
+		i := 1
+		orig := &tCopy{
+			B: []string{"x"},
+			C: map[string]interface{}{"y": []interface{}{2}},
+			D: &tCopy{A: 2},
+			E: [2]*int{&i, nil},
+			f: []int{1},
+			g: &tCopy{A: 3},
+		}
+		c := DeepCopy(orig).(*tCopy)
+		c.B[0] = "changed"
+		c.C["y"].([]interface{})[0] = "changed"
+		c.D.A = 20
+		*c.E[0] = 10
+		c.f[0] = 10
+		c.g.A = 30
+		So(orig.B[0], ShouldEqual, "x")
+		So(orig.C["y"].([]interface{})[0], ShouldEqual, 2)
+		So(orig.D.A, ShouldEqual, 2)
+		So(i, ShouldEqual, 1)
+		So(orig.f[0], ShouldEqual, 1)
+		So(orig.g.A, ShouldEqual, 3)
+	})
+	Convey("Preserve aliasing", t, func() {
+		shared := &tCopy{A: 1}
+		orig := []*tCopy{shared, shared}
+		c := DeepCopy(orig).([]*tCopy)
+		So(c[0], ShouldPointTo, c[1])
+		So(c[0], ShouldNotPointTo, shared)
+	})
+	Convey("Preserve cycles", t, func() {
+		orig := &tCopy{A: 1}
+		orig.D = orig
+		c := DeepCopy(orig).(*tCopy)
+		So(c.D, ShouldPointTo, c)
+		So(c, ShouldNotPointTo, orig)
+
+		m := map[string]interface{}{"a": 1}
+		m["self"] = m
+		mc := DeepCopy(m).(map[string]interface{})
+		mc["a"] = 2
+		So(mc["self"].(map[string]interface{})["a"], ShouldEqual, 2)
+		So(m["a"], ShouldEqual, 1)
+	})
+}
+
+func TestDeepCopy_Cloner(t *testing.T) {
+	Convey("Use custom cloners", t, func() {
+		clones := 0
+		orig := map[string]interface{}{"foo": &tCloner{Name: "foo", Clones: &clones}}
+		c := DeepCopy(orig).(map[string]interface{})
+		So(c["foo"].(*tCloner).Name, ShouldEqual, "foo (clone)")
+		So(clones, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,222 @@
+package reflekt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CSVOptions configure reading and writing CSV
+type CSVOptions struct {
+
+	// Columns are the columns to write, in order. If empty, the fields of structs are written in
+	// order, followed by all other keys, sorted.
+	Columns []string
+
+	// Comma is the field delimiter, `,` if zero
+	Comma rune
+}
+
+// CSVError is an error in a single cell
+type CSVError struct {
+
+	// Row is the line of the record, starting with 1 for the header
+	Row int
+
+	// Column is the header of the column
+	Column string
+
+	// Err is the cause
+	Err error
+}
+
+func (this *CSVError) Error() string {
+	return fmt.Sprintf("Row %d, column %s: %s", this.Row, this.Column, this.Err)
+}
+
+// CSVErrors aggregates the errors of all cells which could not be read
+type CSVErrors struct {
+	Cells []*CSVError
+}
+
+func (this *CSVErrors) Error() string {
+	msgs := make([]string, len(this.Cells))
+	for i, c := range this.Cells {
+		msgs[i] = c.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// csvColumns returns the dotted keys of the exported fields of the struct type in order, named as
+// by StructAsMap in snake case
+func csvColumns(t reflect.Type, prefix string, res []string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return append(res, strings.TrimSuffix(prefix, "."))
+	}
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if ft.PkgPath != "" {
+			continue
+		} else if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+			res = csvColumns(ft.Type, prefix, res)
+		} else {
+			res = csvColumns(ft.Type, prefix+snakeCase(ft.Name)+".", res)
+		}
+	}
+	return res
+}
+
+// WriteCSV writes a slice of structs or maps as CSV with a header. Structs are converted with
+// StructAsMap in snake case, nested structs and maps are flattened into dotted columns, slices
+// into columns with indices.
+func WriteCSV(w io.Writer, slice interface{}, opts *CSVOptions) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	r := indirect(reflectValue(slice))
+	if !isListKind(r.Kind()) {
+		return fmt.Errorf("Expected slice, got %T", slice)
+	}
+
+	rows := make([]map[string]string, r.Len())
+	all := make(map[string]bool)
+	for i := range rows {
+		rows[i] = make(map[string]string)
+		if err := flattenText(r.Index(i), "", rows[i]); err != nil {
+			return fmt.Errorf("Row %d: %s", i+2, err)
+		}
+		for k := range rows[i] {
+			all[k] = true
+		}
+	}
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = []string{}
+		if et := r.Type().Elem(); indirectType(et).Kind() == reflect.Struct {
+			for _, c := range csvColumns(et, "", nil) {
+				if all[c] {
+					columns = append(columns, c)
+					delete(all, c)
+				}
+			}
+		}
+		rest := make([]string, 0, len(all))
+		for c := range all {
+			rest = append(rest, c)
+		}
+		sort.Strings(rest)
+		columns = append(columns, rest...)
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, c := range columns {
+			record[i] = row[c]
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads CSV with a header into the slice, which must be a pointer to a slice of structs,
+// struct pointers or maps. Dotted columns are unflattened into nested maps, which are used to fill
+// structs like Fill. Strings in numeric and bool fields must be parsable as such. Returns
+// *CSVErrors listing all cells which could not be read.
+func (this *StructFiller) ReadCSV(r io.Reader, slice interface{}, opts *CSVOptions) error {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	rv := reflect.ValueOf(slice)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("Expected pointer to slice, got %T", slice)
+	}
+	sv := rv.Elem()
+	et := sv.Type().Elem()
+	st := indirectType(et)
+	if st.Kind() != reflect.Struct && st.Kind() != reflect.Map && st.Kind() != reflect.Interface {
+		return fmt.Errorf("Expected slice of structs or maps, got %T", slice)
+	}
+
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	columns := make([][]string, len(header))
+	for i, h := range header {
+		columns[i] = strings.Split(strings.TrimSpace(h), ".")
+	}
+
+	errs := []*CSVError{}
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		d := make(map[string]interface{})
+		for i, path := range columns {
+			if i >= len(record) {
+				break
+			}
+			m := d
+			for _, seg := range path[:len(path)-1] {
+				sub, ok := m[seg].(map[string]interface{})
+				if !ok {
+					sub = make(map[string]interface{})
+					m[seg] = sub
+				}
+				m = sub
+			}
+			m[path[len(path)-1]] = record[i]
+		}
+		d = indexedMaps(d)
+
+		e := reflect.New(st)
+		if st.Kind() == reflect.Struct {
+			for _, fe := range this.fillChecked(e.Elem(), d, "", nil) {
+				errs = append(errs, &CSVError{Row: row, Column: fe.Field, Err: fe.Err})
+			}
+		} else if err := this.assign(e.Elem(), d, "", "", ""); err != nil {
+			errs = append(errs, &CSVError{Row: row, Column: "", Err: err})
+		}
+		if et.Kind() == reflect.Ptr {
+			sv.Set(reflect.Append(sv, e))
+		} else {
+			sv.Set(reflect.Append(sv, e.Elem()))
+		}
+	}
+	if len(errs) > 0 {
+		return &CSVErrors{errs}
+	}
+	return nil
+}
+
+// ReadCSV reads CSV with a header into the slice, using a StructFiller which reads columns from
+// the `csv` tag. See StructFiller.ReadCSV.
+func ReadCSV(r io.Reader, slice interface{}, opts *CSVOptions) error {
+	return NewStructFiller().UseTag("csv").ReadCSV(r, slice, opts)
+}
@@ -0,0 +1,97 @@
+package reflekt
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tCSVAddress struct {
+	City string
+	Zip  string
+}
+
+type tCSVRow struct {
+	Name    string
+	Age     int
+	Active  bool
+	Since   time.Time
+	Address tCSVAddress
+	Tags    []string
+	Ratio   float64 `csv:"score"`
+}
+
+func TestWriteCSV(t *testing.T) {
+	Convey("Write slice of structs", t, func() {
+		buf := new(bytes.Buffer)
+		err := WriteCSV(buf, []*tCSVRow{
+			{Name: "foo", Age: 42, Active: true, Since: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Address: tCSVAddress{City: "Berlin, DE"}, Tags: []string{"a", "b"}, Ratio: 0.5},
+			{Name: "bar \"x\""},
+			nil,
+		}, nil)
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, `name,age,active,since,address.city,address.zip,ratio,tags.0,tags.1
+foo,42,true,2024-01-02T03:04:05Z,"Berlin, DE",,0.5,a,b
+"bar ""x""",0,false,0001-01-01T00:00:00Z,,,0,,
+,,,,,,,,
+`)
+	})
+	Convey("Write slice of maps with column order", t, func() {
+		buf := new(bytes.Buffer)
+		err := WriteCSV(buf, []map[string]interface{}{
+			{"b": 1, "a": map[string]interface{}{"x": "y"}},
+			{"c": true, "b": 2},
+		}, &CSVOptions{Columns: []string{"b", "a.x", "c"}, Comma: ';'})
+		So(err, ShouldBeNil)
+		So(buf.String(), ShouldEqual, "b;a.x;c\n1;y;\n2;;true\n")
+	})
+	Convey("Fail on invalid values", t, func() {
+		So(WriteCSV(new(bytes.Buffer), map[string]int{}, nil), ShouldNotBeNil)
+		So(WriteCSV(new(bytes.Buffer), []interface{}{map[string]interface{}{"f": func() {}}}, nil), ShouldNotBeNil)
+	})
+}
+
+func TestReadCSV(t *testing.T) {
+	Convey("Read into slice of structs", t, func() {
+		rows := []tCSVRow{}
+		err := ReadCSV(strings.NewReader(`name,age,active,since,address.city,score,tags.0,tags.1,unknown
+foo,42,true,2024-01-02T03:04:05Z,"Berlin, DE",0.5,a,b,x
+bar,,0,,,,,,
+`), &rows, nil)
+		So(err, ShouldBeNil)
+		So(rows, ShouldResemble, []tCSVRow{
+			{Name: "foo", Age: 42, Active: true, Since: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Address: tCSVAddress{City: "Berlin, DE"}, Tags: []string{"a", "b"}, Ratio: 0.5},
+			{Name: "bar", Tags: []string{"", ""}},
+		})
+	})
+	Convey("Read into slice of maps", t, func() {
+		rows := []map[string]interface{}{}
+		So(ReadCSV(strings.NewReader("a;b.c\n1;2\n"), &rows, &CSVOptions{Comma: ';'}), ShouldBeNil)
+		So(rows, ShouldResemble, []map[string]interface{}{{"a": "1", "b": map[string]interface{}{"c": "2"}}})
+	})
+	Convey("Read numeric headers", t, func() {
+		rows := []map[string]interface{}{}
+		So(ReadCSV(strings.NewReader("0,1\na,b\n"), &rows, nil), ShouldBeNil)
+		So(rows, ShouldResemble, []map[string]interface{}{{"0": "a", "1": "b"}})
+	})
+	Convey("Round trip", t, func() {
+		from := []*tCSVRow{{Name: "foo", Age: 1, Address: tCSVAddress{Zip: "123"}, Since: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}}
+		buf := new(bytes.Buffer)
+		So(WriteCSV(buf, from, nil), ShouldBeNil)
+		to := []*tCSVRow{}
+		So(NewStructFiller().ReadCSV(buf, &to, nil), ShouldBeNil)
+		So(to, ShouldResemble, from)
+	})
+	Convey("Report cell errors", t, func() {
+		rows := []tCSVRow{}
+		err := ReadCSV(strings.NewReader("name,age,active,since,address.zip\nfoo,1,1,,1\nbar,old,maybe,soon,2\n"), &rows, nil)
+		So(err, ShouldHaveSameTypeAs, &CSVErrors{})
+		So(err.Error(), ShouldEqual, `Row 3, column age: Expected number, got "old"; Row 3, column active: Expected bool, got "maybe"; Row 3, column since: Cannot parse since: parsing time "soon" as "2006-01-02T15:04:05Z07:00": cannot parse "soon" as "2006"`)
+		So(len(rows), ShouldEqual, 2)
+		So(ReadCSV(strings.NewReader(""), &rows, nil), ShouldBeNil)
+		So(ReadCSV(strings.NewReader("a\n1\n"), rows, nil), ShouldNotBeNil)
+		So(ReadCSV(strings.NewReader("a\n1\n"), &[]int{}, nil), ShouldNotBeNil)
+	})
+}
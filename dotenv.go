@@ -0,0 +1,514 @@
+package reflekt
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	valuerType        = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	dotenvPlainRegex  = regexp.MustCompile(`^[A-Za-z0-9_./:@,+=%-]*$`)
+	dotenvEscaper     = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, `$`, `\$`)
+)
+
+// DotenvQuote returns the value as it is written in dotenv files: unquoted if it contains only
+// safe characters, in single quotes if it contains no single quotes or line breaks and in
+// double quotes with backslash escapes otherwise
+func DotenvQuote(s string) string {
+	if dotenvPlainRegex.MatchString(s) {
+		return s
+	} else if !strings.ContainsAny(s, "'\n\r") {
+		return "'" + s + "'"
+	}
+	return `"` + dotenvEscaper.Replace(s) + `"`
+}
+
+// scalarText formats a scalar value as text, as read by FillEnv, reports false for nil values
+func scalarText(r reflect.Value) (string, bool, error) {
+	r = indirect(r)
+	if !r.IsValid() {
+		return "", false, nil
+	} else if !r.CanInterface() {
+		return "", false, fmt.Errorf("Cannot export unexported %s", r.Type())
+	}
+	v := r.Interface()
+	if r.Type().Implements(valuerType) {
+		if v = sqlValue(v); v == nil {
+			return "", false, nil
+		}
+		return AsString(v), true, nil
+	} else if m, ok := v.(encoding.TextMarshaler); ok {
+		raw, err := m.MarshalText()
+		return string(raw), true, err
+	} else if s, ok := v.(fmt.Stringer); ok && r.Type() != valueType {
+		return s.String(), true, nil
+	} else if r.Type() == valueType {
+		return scalarText(reflect.ValueOf(r.Interface().(Value).v))
+	} else if b, ok := v.([]byte); ok {
+		return string(b), true, nil
+	} else if !IsScalar(r) {
+		return "", false, fmt.Errorf("Cannot export %s as scalar", r.Type())
+	}
+	return AsString(r), true, nil
+}
+
+// isSectionValue checks whether the struct is encoded as multiple entries, with its name as prefix or
+// section, instead of a scalar
+func isSectionValue(r reflect.Value) bool {
+	t := r.Type()
+	return r.Kind() == reflect.Struct && t != timeType && t != valueType &&
+		!t.Implements(valuerType) && !t.Implements(textMarshalerType)
+}
+
+// dotenvList formats a slice or map field as read by FillEnv, reports false for nil values
+func dotenvList(r reflect.Value, sep string) (string, bool, error) {
+	items := []string{}
+	switch r.Kind() {
+	case reflect.Slice, reflect.Array:
+		if r.Kind() == reflect.Slice && r.IsNil() {
+			return "", false, nil
+		}
+		for i := 0; i < r.Len(); i++ {
+			s, _, err := scalarText(r.Index(i))
+			if err != nil {
+				return "", false, err
+			}
+			items = append(items, s)
+		}
+	case reflect.Map:
+		if r.IsNil() {
+			return "", false, nil
+		}
+		for _, k := range r.MapKeys() {
+			ks, _, err := scalarText(k)
+			if err != nil {
+				return "", false, err
+			}
+			vs, _, err := scalarText(r.MapIndex(k))
+			if err != nil {
+				return "", false, err
+			}
+			items = append(items, ks+"="+vs)
+		}
+		sort.Strings(items)
+	}
+	return strings.Join(items, sep), true, nil
+}
+
+type dotenvVar struct {
+	name  string
+	value string
+}
+
+// dotenvVars collects the variables of the struct or map, in field order or sorted by key
+func (this *StructFiller) dotenvVars(r reflect.Value, prefix string, res []dotenvVar) ([]dotenvVar, error) {
+	r = indirect(r)
+	var err error
+	add := func(name string, v reflect.Value, sep string) error {
+		v = indirect(v)
+		if v.IsValid() && v.Type() == valueType {
+			v = indirect(reflect.ValueOf(v.Interface().(Value).v))
+		}
+		var s string
+		ok := false
+		switch {
+		case !v.IsValid():
+			return nil
+		case isSectionValue(v) || (v.Kind() == reflect.Map && r.Kind() == reflect.Map):
+			res, err = this.dotenvVars(v, name+"_", res)
+			return err
+		case v.Kind() == reflect.Map || (isListKind(v.Kind()) && v.Type().Elem().Kind() != reflect.Uint8):
+			s, ok, err = dotenvList(v, sep)
+		default:
+			s, ok, err = scalarText(v)
+		}
+		if err != nil {
+			return fmt.Errorf("Cannot export %s: %s", name, err)
+		} else if ok {
+			res = append(res, dotenvVar{name, s})
+		}
+		return nil
+	}
+
+	switch r.Kind() {
+	case reflect.Struct:
+		t := r.Type()
+		for i := 0; i < t.NumField(); i++ {
+			ft := t.Field(i)
+			if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+				if res, err = this.dotenvVars(r.Field(i), prefix, res); err != nil {
+					return res, err
+				}
+				continue
+			} else if ft.PkgPath != "" {
+				continue
+			}
+			name := this.envName(ft)
+			if name == "" {
+				continue
+			}
+			sep := ft.Tag.Get("sep")
+			if sep == "" {
+				sep = ","
+			}
+			if err := add(prefix+name, r.Field(i), sep); err != nil {
+				return res, err
+			}
+		}
+	case reflect.Map:
+		keys := r.MapKeys()
+		names := make(map[string]reflect.Value, len(keys))
+		sorted := make([]string, len(keys))
+		for i, k := range keys {
+			sorted[i] = strings.ToUpper(snakeCase(AsString(k)))
+			names[sorted[i]] = r.MapIndex(k)
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			if err := add(prefix+name, names[name], ","); err != nil {
+				return res, err
+			}
+		}
+	default:
+		return res, fmt.Errorf("Expected struct or map, got %s", r.Kind())
+	}
+	return res, nil
+}
+
+// MarshalDotenv returns `KEY=value` lines for the struct or map, as read by FillEnv. Names are
+// the field names or map keys in upper snake case, or the tag of the filler, prefixed with the
+// prefix and an underscore, if set. Nested structs and maps use the name of the parent as prefix.
+// Slices of struct fields are joined with `,` or the `sep` tag, maps of struct fields written as
+// `K=V` lists. Nil values are omitted. Fails if two fields or keys have the same name.
+func (this *StructFiller) MarshalDotenv(v interface{}, prefix string) ([]byte, error) {
+	if prefix != "" {
+		prefix += "_"
+	}
+	vars, err := this.dotenvVars(reflectValue(v), prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(vars))
+	buf := new(bytes.Buffer)
+	for _, v := range vars {
+		if names[v.name] {
+			return nil, fmt.Errorf("Cannot export %s more than once", v.name)
+		}
+		names[v.name] = true
+		buf.WriteString(v.name + "=" + DotenvQuote(v.value) + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalDotenv returns `KEY=value` lines for the struct or map, using a StructFiller which reads
+// variable names from the `env` tag. See StructFiller.MarshalDotenv.
+func MarshalDotenv(v interface{}, prefix string) ([]byte, error) {
+	return NewStructFiller().UseTag("env").MarshalDotenv(v, prefix)
+}
+
+// DotenvOptions configure parsing of dotenv files
+type DotenvOptions struct {
+
+	// Lookup resolves interpolated variables which are not defined before in the file. If nil,
+	// such variables are empty.
+	Lookup EnvLookup
+
+	// Nested, if set, is the separator by which keys are split into nested maps, eg "__" for
+	// `DB__HOST=..`
+	Nested string
+
+	// Lower lowercases all keys, so that they match field names in snake case
+	Lower bool
+}
+
+type dotenvParser struct {
+	src  []rune
+	pos  int
+	line int
+	vars map[string]string
+	opts *DotenvOptions
+}
+
+func (this *dotenvParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Line %d: %s", this.line, fmt.Sprintf(format, args...))
+}
+
+func (this *dotenvParser) eof() bool {
+	return this.pos >= len(this.src)
+}
+
+func (this *dotenvParser) peek() rune {
+	if this.eof() {
+		return 0
+	}
+	return this.src[this.pos]
+}
+
+func (this *dotenvParser) next() rune {
+	c := this.peek()
+	this.pos++
+	if c == '\n' {
+		this.line++
+	}
+	return c
+}
+
+func (this *dotenvParser) skipSpaces() {
+	for c := this.peek(); c == ' ' || c == '\t'; c = this.peek() {
+		this.next()
+	}
+}
+
+// skipLine skips to the start of the next line, failing on anything but whitespace or a comment
+func (this *dotenvParser) skipLine() error {
+	this.skipSpaces()
+	if c := this.peek(); c == '#' {
+		for !this.eof() && this.peek() != '\n' {
+			this.next()
+		}
+	} else if c == '\r' {
+		this.next()
+	}
+	if !this.eof() && this.next() != '\n' {
+		return this.errorf("Unexpected characters after value")
+	}
+	return nil
+}
+
+func isDotenvKeyRune(c rune, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(!first && (c == '.' || c == '-' || (c >= '0' && c <= '9')))
+}
+
+func (this *dotenvParser) key() string {
+	start := this.pos
+	for !this.eof() && isDotenvKeyRune(this.peek(), this.pos == start) {
+		this.next()
+	}
+	return string(this.src[start:this.pos])
+}
+
+// lookup returns the value of a variable defined before or from the lookup of the options
+func (this *dotenvParser) lookup(name string) (string, bool) {
+	if v, ok := this.vars[name]; ok {
+		return v, true
+	} else if this.opts.Lookup != nil {
+		return this.opts.Lookup(name)
+	}
+	return "", false
+}
+
+// interpolate replaces `$VAR`, `${VAR}` and `${VAR:-default}` in the runes
+func (this *dotenvParser) interpolate(s []rune) (string, error) {
+	res := []rune{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			res = append(res, s[i])
+			continue
+		}
+		if s[i+1] == '{' {
+			end := -1
+			for j := i + 2; j < len(s); j++ {
+				if s[j] == '}' {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return "", this.errorf("Unterminated variable reference")
+			}
+			name, def := string(s[i+2:end]), ""
+			hasDef := false
+			if p := strings.Index(name, ":-"); p >= 0 {
+				name, def, hasDef = name[:p], name[p+2:], true
+			}
+			if v, ok := this.lookup(name); ok && (v != "" || !hasDef) {
+				res = append(res, []rune(v)...)
+			} else {
+				res = append(res, []rune(def)...)
+			}
+			i = end
+		} else if isDotenvKeyRune(s[i+1], true) {
+			j := i + 1
+			for j < len(s) && isDotenvKeyRune(s[j], j == i+1) && s[j] != '.' && s[j] != '-' {
+				j++
+			}
+			v, _ := this.lookup(string(s[i+1 : j]))
+			res = append(res, []rune(v)...)
+			i = j - 1
+		} else {
+			res = append(res, s[i])
+		}
+	}
+	return string(res), nil
+}
+
+// value parses a single quoted, double quoted or unquoted value
+func (this *dotenvParser) value() (string, error) {
+	switch this.peek() {
+	case '\'':
+		this.next()
+		start := this.pos
+		for !this.eof() && this.peek() != '\'' {
+			this.next()
+		}
+		if this.eof() {
+			return "", this.errorf("Unterminated single quoted value")
+		}
+		v := string(this.src[start:this.pos])
+		this.next()
+		return v, this.skipLine()
+	case '"':
+		this.next()
+		res := []rune{}
+		for {
+			if this.eof() {
+				return "", this.errorf("Unterminated double quoted value")
+			}
+			c := this.next()
+			if c == '"' {
+				break
+			} else if c == '\\' && !this.eof() {
+				switch e := this.next(); e {
+				case 'n':
+					res = append(res, '\n')
+				case 'r':
+					res = append(res, '\r')
+				case 't':
+					res = append(res, '\t')
+				case '\\', '"':
+					res = append(res, e)
+				case '$':
+					// placeholder, so that it is not interpolated
+					res = append(res, 0)
+				default:
+					res = append(res, '\\', e)
+				}
+				continue
+			}
+			res = append(res, c)
+		}
+		v, err := this.interpolate(res)
+		if err != nil {
+			return "", err
+		}
+		return strings.Replace(v, "\x00", "$", -1), this.skipLine()
+	}
+	start := this.pos
+	for !this.eof() && this.peek() != '\n' {
+		if c := this.peek(); c == '#' && (this.pos == start || this.src[this.pos-1] == ' ' || this.src[this.pos-1] == '\t') {
+			break
+		}
+		this.next()
+	}
+	v, err := this.interpolate([]rune(strings.TrimSpace(string(this.src[start:this.pos]))))
+	if err != nil {
+		return "", err
+	}
+	return v, this.skipLine()
+}
+
+// parse returns all variables in order of the file
+func (this *dotenvParser) parse() ([]dotenvVar, error) {
+	res := []dotenvVar{}
+	for !this.eof() {
+		this.skipSpaces()
+		if c := this.peek(); c == '#' || c == '\n' || c == '\r' || this.eof() {
+			if err := this.skipLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		name := this.key()
+		if name == "export" && (this.peek() == ' ' || this.peek() == '\t') {
+			this.skipSpaces()
+			name = this.key()
+		}
+		if name == "" {
+			return nil, this.errorf("Expected variable name")
+		}
+		this.skipSpaces()
+		if this.next() != '=' {
+			return nil, this.errorf("Expected = after %s", name)
+		}
+		this.skipSpaces()
+		v, err := this.value()
+		if err != nil {
+			return nil, err
+		}
+		this.vars[name] = v
+		res = append(res, dotenvVar{name, v})
+	}
+	return res, nil
+}
+
+// ParseDotenv parses the contents of a dotenv file into a map of strings. Supported are comments,
+// the `export` prefix, single quoted literal values, double quoted values with backslash escapes,
+// both spanning multiple lines, and interpolation of `$VAR`, `${VAR}` and `${VAR:-default}` in
+// unquoted and double quoted values. With the Nested option, keys are split into nested maps.
+func ParseDotenv(data []byte, opts *DotenvOptions) (map[string]interface{}, error) {
+	if opts == nil {
+		opts = &DotenvOptions{}
+	}
+	p := &dotenvParser{
+		src:  []rune(string(data)),
+		line: 1,
+		vars: make(map[string]string),
+		opts: opts,
+	}
+	vars, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]interface{})
+	for _, v := range vars {
+		name := v.name
+		if opts.Lower {
+			name = strings.ToLower(name)
+		}
+		path := []string{name}
+		if opts.Nested != "" {
+			path = strings.Split(name, opts.Nested)
+		}
+		m := res
+		for i, seg := range path[:len(path)-1] {
+			sub, ok := m[seg].(map[string]interface{})
+			if !ok {
+				if _, exists := m[seg]; exists {
+					return nil, fmt.Errorf("Key %s conflicts with %s", v.name, strings.Join(path[:i+1], opts.Nested))
+				}
+				sub = make(map[string]interface{})
+				m[seg] = sub
+			}
+			m = sub
+		}
+		last := path[len(path)-1]
+		if _, ok := m[last].(map[string]interface{}); ok {
+			return nil, fmt.Errorf("Key %s conflicts with nested keys", v.name)
+		}
+		m[last] = v.value
+	}
+	return res, nil
+}
+
+// ReadDotenv reads a dotenv file into a Value. See ParseDotenv.
+func ReadDotenv(r io.Reader, opts *DotenvOptions) (*Value, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseDotenv(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewValue(m), nil
+}
@@ -0,0 +1,177 @@
+package reflekt
+
+import (
+	"database/sql"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testsDotenvQuote = []struct {
+	from string
+	to   string
+}{
+	{from: "", to: ""},
+	{from: "foo", to: "foo"},
+	{from: "http://host:80/a,b=c", to: "http://host:80/a,b=c"},
+	{from: "foo bar", to: "'foo bar'"},
+	{from: "$HOME #x", to: "'$HOME #x'"},
+	{from: `it's "quoted"`, to: `"it's \"quoted\""`},
+	{from: "a\nb\\c$d", to: `"a\nb\\c\$d"`},
+}
+
+func TestDotenvQuote(t *testing.T) {
+	Convey("Quote dotenv values", t, func() {
+		for i, test := range testsDotenvQuote {
+			Convey(fmt.Sprintf("(%d) Quote %q", i+1, test.from), func() {
+				So(DotenvQuote(test.from), ShouldEqual, test.to)
+			})
+		}
+	})
+}
+
+func TestMarshalDotenv(t *testing.T) {
+	Convey("Marshal struct", t, func() {
+		three := 3
+		c := &tEnvConfig{
+			tEnvBase: tEnvBase{Debug: true},
+			Name:     "my svc",
+			Port:     8080,
+			Ratio:    0.5,
+			Timeout:  time.Minute,
+			Since:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Hosts:    []string{"a", "b"},
+			Ports:    []int{1, 2},
+			Limits:   map[string]int{"y": 2, "x": 1},
+			Optional: &three,
+			Null:     sql.NullString{},
+			Skip:     "nope",
+			DB:       tEnvDB{Host: "localhost", MaxConns: 10},
+		}
+		raw, err := MarshalDotenv(c, "APP")
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, `APP_DEBUG=true
+APP_NAME='my svc'
+APP_PORT=8080
+APP_RATIO=0.5
+APP_TIMEOUT=1m0s
+APP_SINCE=2024-01-02T03:04:05Z
+APP_HOSTS=a,b
+APP_PORTS='1;2'
+APP_LIMITS=x=1,y=2
+APP_OPTIONAL=3
+APP_DB_HOST=localhost
+APP_DB_CONNS=10
+`)
+
+		Convey("Round trip with FillEnv", func() {
+			env := map[string]string{}
+			for _, line := range []string{"APP_NAME=my svc", "APP_PORTS=1;2", "APP_LIMITS=x=1,y=2", "APP_TIMEOUT=1m0s", "APP_DB_CONNS=10"} {
+				p := strings.SplitN(line, "=", 2)
+				env[p[0]] = p[1]
+			}
+			to := &tEnvConfig{}
+			So(FillEnv(to, "APP", EnvMap(env)), ShouldBeNil)
+			So(to.Ports, ShouldResemble, c.Ports)
+			So(to.Limits, ShouldResemble, c.Limits)
+			So(to.Timeout, ShouldEqual, c.Timeout)
+			So(to.DB.MaxConns, ShouldEqual, c.DB.MaxConns)
+		})
+	})
+	Convey("Marshal nested map", t, func() {
+		raw, err := MarshalDotenv(map[string]interface{}{
+			"name":  "svc",
+			"db":    map[string]interface{}{"maxConns": 5, "host": nil},
+			"tags":  []interface{}{"a", 1},
+			"value": NewValue("x y"),
+		}, "")
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, "DB_MAX_CONNS=5\nNAME=svc\nTAGS=a,1\nVALUE='x y'\n")
+	})
+	Convey("Fail on unsupported values", t, func() {
+		_, err := MarshalDotenv(map[string]interface{}{"f": func() {}}, "")
+		So(err, ShouldNotBeNil)
+		_, err = MarshalDotenv(1, "")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Fail on duplicate names", t, func() {
+		_, err := MarshalDotenv(struct {
+			User   string `env:"USER_ID"`
+			UserID int
+		}{"bob", 5}, "")
+		So(err, ShouldNotBeNil)
+		_, err = MarshalDotenv(map[string]interface{}{"userId": 1, "user_id": 2}, "")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Export fields with acronyms", t, func() {
+		raw, err := MarshalDotenv(struct {
+			User   string
+			UserID int
+		}{"bob", 5}, "")
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, "USER=bob\nUSER_ID=5\n")
+	})
+}
+
+func TestParseDotenv(t *testing.T) {
+	Convey("Parse dotenv file", t, func() {
+		m, err := ParseDotenv([]byte(`# comment
+export NAME=svc
+EMPTY=
+SPACED =  foo bar   # trailing comment
+HASH=a#b
+SINGLE='literal $NAME \n'  # comment
+DOUBLE="tab\there \"quoted\" \$NAME"
+MULTI="line 1
+line 2"
+MULTI_SINGLE='a
+b'
+REF=${NAME}-$NAME.x
+DEFAULT=${UNSET:-fallback}
+EXTERNAL=$HOME
+CRLF=yes`+"\r\n"), &DotenvOptions{Lookup: EnvMap(map[string]string{"HOME": "/home"})})
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{
+			"NAME":         "svc",
+			"EMPTY":        "",
+			"SPACED":       "foo bar",
+			"HASH":         "a#b",
+			"SINGLE":       `literal $NAME \n`,
+			"DOUBLE":       "tab\there \"quoted\" $NAME",
+			"MULTI":        "line 1\nline 2",
+			"MULTI_SINGLE": "a\nb",
+			"REF":          "svc-svc.x",
+			"DEFAULT":      "fallback",
+			"EXTERNAL":     "/home",
+			"CRLF":         "yes",
+		})
+	})
+	Convey("Unflatten nested keys", t, func() {
+		v, err := ReadDotenv(strings.NewReader("DB__HOST=localhost\nDB__MAX_CONNS=5\nNAME=svc\n"), &DotenvOptions{Nested: "__", Lower: true})
+		So(err, ShouldBeNil)
+		So(v.Interface(), ShouldResemble, map[string]interface{}{
+			"db":   map[string]interface{}{"host": "localhost", "max_conns": "5"},
+			"name": "svc",
+		})
+		c := &tFlagConfig{}
+		So(NewStructFiller().Fill(c, v.InterfaceMap()), ShouldBeNil)
+		So(c.Name, ShouldEqual, "svc")
+		So(c.DB, ShouldResemble, tFlagDB{Host: "localhost", MaxConns: 5})
+	})
+	Convey("Round trip with MarshalDotenv", t, func() {
+		from := map[string]interface{}{"a": "it's \"x\"\n$HOME", "b": "$HOME #x", "c": "plain"}
+		raw, err := MarshalDotenv(from, "")
+		So(err, ShouldBeNil)
+		m, err := ParseDotenv(raw, &DotenvOptions{Lookup: EnvMap(map[string]string{"HOME": "/home"}), Lower: true})
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, from)
+	})
+	Convey("Fail on invalid files", t, func() {
+		for _, raw := range []string{"=x", "A", "A='x", `A="x`, "A='x' y", "A=${X", "A__B=1\nA=2", "A=1\nA__B=2"} {
+			_, err := ParseDotenv([]byte(raw), &DotenvOptions{Nested: "__"})
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
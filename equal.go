@@ -0,0 +1,291 @@
+package reflekt
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EqualOptions configure the comparison of LooseEqual
+type EqualOptions struct {
+
+	// FloatTolerance is the maximum absolute difference of two numbers to be considered equal, if
+	// at least one of them is a float. Integers are compared exactly.
+	FloatTolerance float64
+
+	// IgnorePaths contains path patterns which are not compared. See TransformRule for the syntax
+	IgnorePaths []string
+
+	// IgnoreUnexported excludes unexported struct fields from the comparison
+	IgnoreUnexported bool
+
+	// NilEqualsEmpty makes nil equal to empty maps, slices and arrays
+	NilEqualsEmpty bool
+}
+
+type comparer struct {
+	opts *EqualOptions
+	seen map[[2]walkRef]bool
+}
+
+// LooseEqual checks whether both values are equal, using the same leniency as the casters:
+// scalars are compared after conversion, so `1`, `"1"` and `1.0` are equal, map keys are compared
+// as strings, slices and arrays are interchangeable and structs are compared with maps by field
+// names. Options can be nil.
+func LooseEqual(a, b interface{}, opts *EqualOptions) bool {
+	if opts == nil {
+		opts = &EqualOptions{}
+	}
+	c := &comparer{
+		opts: opts,
+		seen: make(map[[2]walkRef]bool),
+	}
+	return c.equal(reflectValue(a), reflectValue(b), []string{})
+}
+
+func (this *comparer) ignored(path []string) bool {
+	for _, p := range this.opts.IgnorePaths {
+		if matchPath(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCollectionKind(k reflect.Kind) bool {
+	return k == reflect.Map || k == reflect.Slice || k == reflect.Array || k == reflect.Struct
+}
+
+func (this *comparer) equal(a, b reflect.Value, path []string) bool {
+	if this.ignored(path) {
+		return true
+	}
+	if a.Kind() == reflect.Ptr && b.Kind() == reflect.Ptr && !a.IsNil() && !b.IsNil() {
+		ref := [2]walkRef{{a.Pointer(), 0, a.Type()}, {b.Pointer(), 0, b.Type()}}
+		if this.seen[ref] {
+			return true
+		}
+		this.seen[ref] = true
+	}
+	a, b = indirect(a), indirect(b)
+
+	if !a.IsValid() || !b.IsValid() || isNilCollection(a) || isNilCollection(b) {
+		return this.equalNil(a, b)
+	}
+
+	ak, bk := a.Kind(), b.Kind()
+	switch {
+	case a.Type() == timeType && b.Type() == timeType && a.CanInterface() && b.CanInterface():
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	case isListKind(ak) && isListKind(bk):
+		return this.equalList(a, b, path)
+	case isCollectionKind(ak) && isCollectionKind(bk):
+		if ak == reflect.Map && bk == reflect.Map {
+			ref := [2]walkRef{{a.Pointer(), 0, a.Type()}, {b.Pointer(), 0, b.Type()}}
+			if this.seen[ref] {
+				return true
+			}
+			this.seen[ref] = true
+		}
+		return this.equalEntries(a, b, path)
+	case isCollectionKind(ak) || isCollectionKind(bk):
+		return false
+	default:
+		return this.equalScalar(a, b)
+	}
+}
+
+func isListKind(k reflect.Kind) bool {
+	return k == reflect.Slice || k == reflect.Array
+}
+
+func isNilCollection(r reflect.Value) bool {
+	return (r.Kind() == reflect.Map || r.Kind() == reflect.Slice) && r.IsNil()
+}
+
+func (this *comparer) equalNil(a, b reflect.Value) bool {
+	nilA, nilB := !a.IsValid() || isNilCollection(a), !b.IsValid() || isNilCollection(b)
+	if nilA && nilB {
+		return true
+	} else if !this.opts.NilEqualsEmpty {
+		return false
+	}
+	other := a
+	if nilA {
+		other = b
+	}
+	switch other.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return other.Len() == 0
+	default:
+		return false
+	}
+}
+
+func (this *comparer) equalList(a, b reflect.Value, path []string) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		if !this.equal(a.Index(i), b.Index(i), appendPath(path, strconv.Itoa(i))) {
+			return false
+		}
+	}
+	return true
+}
+
+// valueEntries returns the map values or struct fields by their name. Fields of embedded
+// structs are added as fields of the embedding struct, as in StructAsMap
+func valueEntries(r reflect.Value, unexported bool, res map[string]reflect.Value) map[string]reflect.Value {
+	if res == nil {
+		res = make(map[string]reflect.Value)
+	}
+	switch r.Kind() {
+	case reflect.Map:
+		for _, k := range r.MapKeys() {
+			res[fmt.Sprintf("%v", indirect(k))] = r.MapIndex(k)
+		}
+	case reflect.Struct:
+		t := r.Type()
+		for i := 0; i < r.NumField(); i++ {
+			ft := t.Field(i)
+			if ft.PkgPath != "" && !unexported {
+				continue
+			} else if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+				valueEntries(r.Field(i), unexported, res)
+			} else {
+				res[ft.Name] = r.Field(i)
+			}
+		}
+	}
+	return res
+}
+
+// lookupEntry finds the field name in entries, also with the names StructFiller accepts
+func lookupEntry(entries map[string]reflect.Value, name string, fuzzy bool) (string, reflect.Value, bool) {
+	names := []string{name}
+	if fuzzy {
+		names = append(names, snakeCase(name), strings.ToLower(name))
+	}
+	for _, n := range names {
+		if v, ok := entries[n]; ok {
+			return n, v, true
+		}
+	}
+	return "", reflect.Value{}, false
+}
+
+func (this *comparer) equalEntries(a, b reflect.Value, path []string) bool {
+	if b.Kind() == reflect.Struct && a.Kind() != reflect.Struct {
+		a, b = b, a
+	}
+	fuzzy := a.Kind() == reflect.Struct && b.Kind() != reflect.Struct
+	ae, be := valueEntries(a, !this.opts.IgnoreUnexported, nil), valueEntries(b, !this.opts.IgnoreUnexported, nil)
+	matched := make(map[string]bool)
+	for n, av := range ae {
+		p := appendPath(path, n)
+		bn, bv, ok := lookupEntry(be, n, fuzzy)
+		if !ok {
+			if this.ignored(p) || (this.opts.NilEqualsEmpty && this.equalNil(reflect.Value{}, indirect(av))) {
+				continue
+			}
+			return false
+		}
+		matched[bn] = true
+		if !this.equal(av, bv, p) {
+			return false
+		}
+	}
+	for n, bv := range be {
+		if matched[n] {
+			continue
+		}
+		p := appendPath(path, n)
+		if this.ignored(p) || (this.opts.NilEqualsEmpty && this.equalNil(reflect.Value{}, indirect(bv))) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+var decimalRegex = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// parseDecimal parses a finite number in decimal or exponent syntax, which excludes words like
+// `NaN` or `Inf` accepted by strconv.ParseFloat
+func parseDecimal(s string) (float64, bool) {
+	if !decimalRegex.MatchString(s) {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// scalarNumber returns the value as float if it is numeric or a string representing a finite
+// decimal number
+func scalarNumber(r reflect.Value) (float64, bool) {
+	k := r.Kind()
+	switch {
+	case IsIntKind(k):
+		return float64(r.Int()), true
+	case IsUintKind(k):
+		return float64(r.Uint()), true
+	case IsFloatKind(k):
+		return r.Float(), true
+	case k == reflect.String:
+		return parseDecimal(strings.TrimSpace(r.String()))
+	}
+	return 0, false
+}
+
+// scalarInteger returns the value as big integer if it is an integer or a string representing one
+func scalarInteger(r reflect.Value) (*big.Int, bool) {
+	k := r.Kind()
+	switch {
+	case IsIntKind(k):
+		return big.NewInt(r.Int()), true
+	case IsUintKind(k):
+		return new(big.Int).SetUint64(r.Uint()), true
+	case k == reflect.String:
+		return new(big.Int).SetString(strings.TrimSpace(r.String()), 10)
+	}
+	return nil, false
+}
+
+func (this *comparer) equalScalar(a, b reflect.Value) bool {
+	ak, bk := a.Kind(), b.Kind()
+	if IsIntKind(ak) && IsIntKind(bk) {
+		return a.Int() == b.Int()
+	} else if IsUintKind(ak) && IsUintKind(bk) {
+		return a.Uint() == b.Uint()
+	} else if ak == reflect.String && bk == reflect.String && a.String() == b.String() {
+		return true
+	} else if ak == reflect.Bool || bk == reflect.Bool {
+		_, numA := scalarNumber(a)
+		_, numB := scalarNumber(b)
+		_, errA := parseBool(AsString(a))
+		_, errB := parseBool(AsString(b))
+		if (numA || errA == nil || ak == reflect.Bool) && (numB || errB == nil || bk == reflect.Bool) {
+			return AsBool(a) == AsBool(b)
+		}
+		return false
+	}
+	if ia, ok := scalarInteger(a); ok {
+		if ib, ok := scalarInteger(b); ok {
+			return ia.Cmp(ib) == 0
+		}
+	}
+	fa, okA := scalarNumber(a)
+	fb, okB := scalarNumber(b)
+	if okA && okB {
+		return fa == fb || math.Abs(fa-fb) <= this.opts.FloatTolerance
+	} else if ak == reflect.String || bk == reflect.String {
+		return AsString(a) == AsString(b)
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package reflekt
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"math"
+	"testing"
+	"time"
+)
+
+type tEqual struct {
+	Name      string
+	FooBar    int
+	Tags      []string
+	Sub       *tEqual
+	Timestamp time.Time
+	hidden    int
+}
+
+var testsLooseEqual = []struct {
+	a     interface{}
+	b     interface{}
+	opts  *EqualOptions
+	equal bool
+}{
+	{a: nil, b: nil, equal: true},
+	{a: 1, b: 1, equal: true},
+	{a: 1, b: "1", equal: true},
+	{a: 1, b: 1.0, equal: true},
+	{a: "1", b: 1.0, equal: true},
+	{a: "1.0", b: "1", equal: true},
+	{a: uint8(3), b: int64(3), equal: true},
+	{a: int64(1<<53 + 1), b: uint64(1 << 53), equal: false},
+	{a: uint64(math.MaxUint64), b: "18446744073709551614", equal: false},
+	{a: int64(-1), b: uint64(math.MaxUint64), equal: false},
+	{a: 1, b: 2, opts: &EqualOptions{FloatTolerance: 5}, equal: false},
+	{a: 1, b: 2, equal: false},
+	{a: "foo", b: "bar", equal: false},
+	{a: "foo", b: 0, equal: false},
+	{a: true, b: 1, equal: true},
+	{a: true, b: "true", equal: true},
+	{a: false, b: "0", equal: true},
+	{a: false, b: "foo", equal: false},
+	{a: 1, b: nil, equal: false},
+	{a: 1, b: []int{1}, equal: false},
+	{a: 1.0, b: 1.05, equal: false},
+	{a: 1.0, b: 1.05, opts: &EqualOptions{FloatTolerance: 0.1}, equal: true},
+	{a: []int{1, 2}, b: []interface{}{"1", 2.0}, equal: true},
+	{a: []int{1, 2}, b: [2]string{"1", "2"}, equal: true},
+	{a: []int{1, 2}, b: []int{1}, equal: false},
+	{
+		a:     map[string]interface{}{"1": "foo", "2": []int{1}},
+		b:     map[interface{}]interface{}{1: "foo", int64(2): []string{"1"}},
+		equal: true,
+	},
+	{
+		a:     map[string]interface{}{"a": 1, "b": 2},
+		b:     map[string]interface{}{"a": 1},
+		equal: false,
+	},
+	{
+		a:     map[string]interface{}{"a": 1, "b": 2},
+		b:     map[string]interface{}{"a": 1, "b": 3},
+		opts:  &EqualOptions{IgnorePaths: []string{"b"}},
+		equal: true,
+	},
+	{
+		a:     map[string]interface{}{"a": map[string]interface{}{"x": 1, "y": 2}},
+		b:     map[string]interface{}{"a": map[string]interface{}{"x": 1}},
+		opts:  &EqualOptions{IgnorePaths: []string{"*.y"}},
+		equal: true,
+	},
+	{a: []int(nil), b: []int{}, equal: false},
+	{a: []int(nil), b: []int{}, opts: &EqualOptions{NilEqualsEmpty: true}, equal: true},
+	{a: nil, b: map[string]int{}, opts: &EqualOptions{NilEqualsEmpty: true}, equal: true},
+	{a: nil, b: []int{1}, opts: &EqualOptions{NilEqualsEmpty: true}, equal: false},
+	{
+		a:     tEqual{Name: "foo", FooBar: 1, hidden: 1},
+		b:     tEqual{Name: "foo", FooBar: 1, hidden: 2},
+		equal: false,
+	},
+	{
+		a:     tEqual{Name: "foo", FooBar: 1, hidden: 1},
+		b:     tEqual{Name: "foo", FooBar: 1, hidden: 2},
+		opts:  &EqualOptions{IgnoreUnexported: true},
+		equal: true,
+	},
+	{
+		a:     &tEqual{Name: "foo", FooBar: 1, Sub: &tEqual{Name: "bar"}},
+		b:     map[string]interface{}{"name": "foo", "foo_bar": "1", "sub": map[string]interface{}{"Name": "bar"}},
+		opts:  &EqualOptions{IgnoreUnexported: true, NilEqualsEmpty: true, IgnorePaths: []string{"**.Timestamp", "Sub.FooBar"}},
+		equal: true,
+	},
+	{
+		a:     tEqual{Timestamp: time.Unix(1000, 0)},
+		b:     tEqual{Timestamp: time.Unix(1000, 0).UTC()},
+		opts:  &EqualOptions{IgnoreUnexported: true},
+		equal: true,
+	},
+}
+
+func TestLooseEqual(t *testing.T) {
+	Convey("Compare values loosely", t, func() {
+		for i, test := range testsLooseEqual {
+			Convey(fmt.Sprintf("%d) %s (%v) vs %s (%v) -> %v", i, typeName(test.a), test.a, typeName(test.b), test.b, test.equal), func() {
+				So(LooseEqual(test.a, test.b, test.opts), ShouldEqual, test.equal)
+				So(LooseEqual(test.b, test.a, test.opts), ShouldEqual, test.equal)
+			})
+		}
+	})
+}
+
+func TestLooseEqual_Cycles(t *testing.T) {
+	Convey("Compare cyclic values", t, func() {
+		a := &tEqual{Name: "foo"}
+		a.Sub = a
+		b := &tEqual{Name: "foo"}
+		b.Sub = b
+		So(LooseEqual(a, b, nil), ShouldBeTrue)
+		b.Name = "bar"
+		So(LooseEqual(a, b, nil), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,421 @@
+package reflekt
+
+import (
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	valueType           = reflect.TypeOf(Value{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// parseFlag parses the string leniently into a value of the type
+func parseFlag(s string, t reflect.Type) (reflect.Value, error) {
+	res := reflect.New(t).Elem()
+	if t == valueType {
+		res.Set(reflect.ValueOf(*NewValue(Infer(s))))
+		return res, nil
+	} else if t == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return res, err
+		}
+		res.SetInt(int64(d))
+		return res, nil
+	} else if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		err := res.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		return res, err
+	}
+
+	k := t.Kind()
+	switch {
+	case k == reflect.String:
+		res.SetString(s)
+	case k == reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return res, fmt.Errorf("Cannot parse %q as bool", s)
+		}
+		res.SetBool(b)
+	case k == reflect.Interface && t.NumMethod() == 0:
+		if v := Infer(s); v != nil {
+			res.Set(reflect.ValueOf(v))
+		}
+	case IsIntKind(k):
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			f, err := parseNumber(s)
+			if err != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return res, fmt.Errorf("Cannot parse %q as %s", s, t)
+			}
+			i = int64(f)
+		}
+		if res.OverflowInt(i) {
+			return res, fmt.Errorf("Value %q overflows %s", s, t)
+		}
+		res.SetInt(i)
+	case IsUintKind(k):
+		u, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			f, err := parseNumber(s)
+			if err != nil || f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return res, fmt.Errorf("Cannot parse %q as %s", s, t)
+			}
+			u = uint64(f)
+		}
+		if res.OverflowUint(u) {
+			return res, fmt.Errorf("Value %q overflows %s", s, t)
+		}
+		res.SetUint(u)
+	case IsFloatKind(k):
+		f, err := parseNumber(s)
+		if err != nil {
+			return res, fmt.Errorf("Cannot parse %q as %s", s, t)
+		} else if res.OverflowFloat(f) {
+			return res, fmt.Errorf("Value %q overflows %s", s, t)
+		}
+		res.SetFloat(f)
+	default:
+		return res, fmt.Errorf("Unsupported flag type %s", t)
+	}
+	return res, nil
+}
+
+// flagString formats a single flag value
+func flagString(r reflect.Value) string {
+	if !r.IsValid() {
+		return ""
+	} else if r.Type() == valueType {
+		return AsString(r.Interface().(Value).v)
+	} else if s, ok := r.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return AsString(r)
+}
+
+// flagTypeName returns the name of the type as used by pflag, eg "int" or "duration"
+func flagTypeName(t reflect.Type) string {
+	switch {
+	case t == valueType || t.Kind() == reflect.Interface:
+		return "value"
+	case t == durationType:
+		return "duration"
+	case t.Name() != "":
+		return t.Name()
+	}
+	return t.String()
+}
+
+// flagTarget returns the settable value the pointer refers to, or an invalid value
+func flagTarget(ptr interface{}) reflect.Value {
+	r := reflectValue(ptr)
+	if r.Kind() != reflect.Ptr || r.IsNil() {
+		return reflect.Value{}
+	}
+	return r.Elem()
+}
+
+// splitFlag splits comma separated values, which can be quoted as in CSV
+func splitFlag(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return []string{}, nil
+	}
+	res, err := csv.NewReader(strings.NewReader(s)).Read()
+	if err == io.EOF {
+		return []string{}, nil
+	}
+	return res, err
+}
+
+// Flag implements flag.Value, flag.Getter and pflag.Value for a scalar, stored into a typed
+// pointer or a *Value. Values are parsed leniently, as by AsBool, AsInt and AsFloat.
+type Flag struct {
+	r reflect.Value
+}
+
+// NewFlag creates a flag storing into the pointer, which must point to a string, bool, number,
+// time.Duration, encoding.TextUnmarshaler, interface{} or Value. A *Value receives the result of
+// Infer.
+func NewFlag(ptr interface{}) *Flag {
+	return &Flag{flagTarget(ptr)}
+}
+
+// Set parses the string and stores it
+func (this *Flag) Set(s string) error {
+	if !this.r.IsValid() {
+		return fmt.Errorf("Flag has no target")
+	}
+	v, err := parseFlag(s, this.r.Type())
+	if err != nil {
+		return err
+	}
+	this.r.Set(v)
+	return nil
+}
+
+// String returns the current value
+func (this *Flag) String() string {
+	if this == nil {
+		return ""
+	}
+	return flagString(this.r)
+}
+
+// Get returns the current value
+func (this *Flag) Get() interface{} {
+	if !this.r.IsValid() {
+		return nil
+	} else if this.r.Type() == valueType {
+		return this.r.Interface().(Value).v
+	}
+	return this.r.Interface()
+}
+
+// Type returns the name of the type, as required by pflag
+func (this *Flag) Type() string {
+	if !this.r.IsValid() {
+		return ""
+	}
+	return flagTypeName(this.r.Type())
+}
+
+// IsBoolFlag allows bool flags to be given without value
+func (this *Flag) IsBoolFlag() bool {
+	return this.r.IsValid() && this.r.Kind() == reflect.Bool
+}
+
+// SliceFlag implements flag.Value, flag.Getter, pflag.Value and pflag.SliceValue for a slice.
+// Each Set parses comma separated values, which are appended. The first Set replaces the default.
+type SliceFlag struct {
+	r       reflect.Value
+	changed bool
+}
+
+// NewSliceFlag creates a flag storing into the pointer, which must point to a slice of types
+// supported by NewFlag or a Value, which receives an []interface{}
+func NewSliceFlag(ptr interface{}) *SliceFlag {
+	return &SliceFlag{r: flagTarget(ptr)}
+}
+
+// slice returns the target slice and its element type
+func (this *SliceFlag) slice() (reflect.Value, error) {
+	if !this.r.IsValid() {
+		return reflect.Value{}, fmt.Errorf("Flag has no target")
+	} else if this.r.Type() == valueType {
+		return reflect.ValueOf(AsInterfaces(this.r.Interface().(Value).v)), nil
+	} else if this.r.Kind() != reflect.Slice {
+		return reflect.Value{}, fmt.Errorf("Expected slice, got %s", this.r.Type())
+	}
+	return this.r, nil
+}
+
+func (this *SliceFlag) store(s reflect.Value) {
+	if this.r.Type() == valueType {
+		this.r.Set(reflect.ValueOf(*NewValue(s.Interface())))
+	} else {
+		this.r.Set(s)
+	}
+}
+
+func (this *SliceFlag) parse(items []string) (reflect.Value, error) {
+	s, err := this.slice()
+	if err != nil {
+		return s, err
+	}
+	res := reflect.MakeSlice(s.Type(), 0, len(items))
+	for _, item := range items {
+		if s.Type().Elem().Kind() != reflect.String {
+			item = strings.TrimSpace(item)
+		}
+		v, err := parseFlag(item, s.Type().Elem())
+		if err != nil {
+			return res, err
+		}
+		res = reflect.Append(res, v)
+	}
+	return res, nil
+}
+
+// Set parses comma separated values and appends them
+func (this *SliceFlag) Set(s string) error {
+	items, err := splitFlag(s)
+	if err != nil {
+		return err
+	}
+	if !this.changed {
+		this.changed = true
+		return this.Replace(items)
+	}
+	return this.Append(items...)
+}
+
+// Append parses the values and appends them
+func (this *SliceFlag) Append(items ...string) error {
+	s, err := this.slice()
+	if err != nil {
+		return err
+	}
+	add, err := this.parse(items)
+	if err != nil {
+		return err
+	}
+	this.store(reflect.AppendSlice(s, add))
+	return nil
+}
+
+// Replace parses the values and replaces all current values
+func (this *SliceFlag) Replace(items []string) error {
+	s, err := this.parse(items)
+	if err != nil {
+		return err
+	}
+	this.store(s)
+	return nil
+}
+
+// GetSlice returns the current values as strings
+func (this *SliceFlag) GetSlice() []string {
+	s, err := this.slice()
+	if err != nil {
+		return []string{}
+	}
+	res := make([]string, s.Len())
+	for i := range res {
+		res[i] = flagString(indirect(s.Index(i)))
+	}
+	return res
+}
+
+// String returns the current values comma separated
+func (this *SliceFlag) String() string {
+	if this == nil {
+		return ""
+	}
+	return "[" + strings.Join(this.GetSlice(), ",") + "]"
+}
+
+// Get returns the current slice
+func (this *SliceFlag) Get() interface{} {
+	s, err := this.slice()
+	if err != nil {
+		return nil
+	}
+	return s.Interface()
+}
+
+// Type returns the name of the type, as required by pflag, eg "intSlice"
+func (this *SliceFlag) Type() string {
+	s, err := this.slice()
+	if err != nil {
+		return ""
+	}
+	return flagTypeName(s.Type().Elem()) + "Slice"
+}
+
+// MapFlag implements flag.Value, flag.Getter and pflag.Value for a map. Each Set parses comma
+// separated `key=value` pairs, which are added. The first Set replaces the default.
+type MapFlag struct {
+	r       reflect.Value
+	changed bool
+}
+
+// NewMapFlag creates a flag storing into the pointer, which must point to a map with keys and
+// values of types supported by NewFlag or a Value, which receives a map[string]interface{}
+func NewMapFlag(ptr interface{}) *MapFlag {
+	return &MapFlag{r: flagTarget(ptr)}
+}
+
+// mapping returns the target map
+func (this *MapFlag) mapping() (reflect.Value, error) {
+	if !this.r.IsValid() {
+		return reflect.Value{}, fmt.Errorf("Flag has no target")
+	} else if this.r.Type() == valueType {
+		return reflect.ValueOf(AsInterfaceMap(this.r.Interface().(Value).v)), nil
+	} else if this.r.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("Expected map, got %s", this.r.Type())
+	}
+	return this.r, nil
+}
+
+// Set parses comma separated `key=value` pairs and adds them
+func (this *MapFlag) Set(s string) error {
+	m, err := this.mapping()
+	if err != nil {
+		return err
+	}
+	items, err := splitFlag(s)
+	if err != nil {
+		return err
+	}
+	if !this.changed || m.IsNil() {
+		m = reflect.MakeMap(m.Type())
+	}
+	for _, item := range items {
+		p := strings.SplitN(item, "=", 2)
+		if len(p) != 2 {
+			return fmt.Errorf("Expected key=value, got %q", item)
+		}
+		k, err := parseFlag(strings.TrimSpace(p[0]), m.Type().Key())
+		if err != nil {
+			return err
+		}
+		v, err := parseFlag(p[1], m.Type().Elem())
+		if err != nil {
+			return err
+		}
+		m.SetMapIndex(k, v)
+	}
+	this.changed = true
+	if this.r.Type() == valueType {
+		this.r.Set(reflect.ValueOf(*NewValue(m.Interface())))
+	} else {
+		this.r.Set(m)
+	}
+	return nil
+}
+
+// String returns the current pairs comma separated, ordered by key
+func (this *MapFlag) String() string {
+	if this == nil {
+		return ""
+	}
+	m, err := this.mapping()
+	if err != nil {
+		return ""
+	}
+	pairs := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		pairs = append(pairs, flagString(k)+"="+flagString(indirect(m.MapIndex(k))))
+	}
+	sort.Strings(pairs)
+	return "[" + strings.Join(pairs, ",") + "]"
+}
+
+// Get returns the current map
+func (this *MapFlag) Get() interface{} {
+	m, err := this.mapping()
+	if err != nil {
+		return nil
+	}
+	return m.Interface()
+}
+
+// Type returns the name of the type, as required by pflag, eg "stringToInt"
+func (this *MapFlag) Type() string {
+	m, err := this.mapping()
+	if err != nil {
+		return ""
+	}
+	v := flagTypeName(m.Type().Elem())
+	return flagTypeName(m.Type().Key()) + "To" + strings.ToUpper(v[:1]) + v[1:]
+}
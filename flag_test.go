@@ -0,0 +1,119 @@
+package reflekt
+
+import (
+	"flag"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+var testsFlag = []struct {
+	ptr  interface{}
+	from string
+	to   interface{}
+	str  string
+	typ  string
+	err  bool
+}{
+	{ptr: new(bool), from: "yes", to: true, str: "true", typ: "bool"},
+	{ptr: new(bool), from: "Off", to: false, str: "false", typ: "bool"},
+	{ptr: new(bool), from: "maybe", err: true},
+	{ptr: new(int), from: "1.5k", to: 1500, str: "1500", typ: "int"},
+	{ptr: new(int), from: "0x10", err: true},
+	{ptr: new(int64), from: "2Ki", to: int64(2048), str: "2048", typ: "int64"},
+	{ptr: new(int8), from: "1k", err: true},
+	{ptr: new(int), from: "1.5", err: true},
+	{ptr: new(uint), from: "3M", to: uint(3000000), str: "3000000", typ: "uint"},
+	{ptr: new(uint), from: "-1", err: true},
+	{ptr: new(float64), from: "1.5g", to: 1.5e9, str: "1.5e+09", typ: "float64"},
+	{ptr: new(string), from: " foo ", to: " foo ", str: " foo ", typ: "string"},
+	{ptr: new(time.Duration), from: "1m30s", to: 90 * time.Second, str: "1m30s", typ: "duration"},
+	{ptr: new(time.Time), from: "2024-01-02T03:04:05Z", to: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), str: "2024-01-02 03:04:05 +0000 UTC", typ: "Time"},
+	{ptr: new(interface{}), from: "42", to: int64(42), str: "42", typ: "value"},
+	{ptr: NewValue(nil), from: "4.5", to: 4.5, str: "4.5", typ: "value"},
+	{ptr: new([]int), from: "1", err: true},
+}
+
+func TestFlag(t *testing.T) {
+	Convey("Set scalar flags", t, func() {
+		for i, test := range testsFlag {
+			Convey(fmt.Sprintf("(%d) Set %q into %s", i+1, test.from, typeName(test.ptr)), func() {
+				f := NewFlag(test.ptr)
+				err := f.Set(test.from)
+				if test.err {
+					So(err, ShouldNotBeNil)
+				} else {
+					So(err, ShouldBeNil)
+					So(f.Get(), ShouldResemble, test.to)
+					So(f.String(), ShouldEqual, test.str)
+					So(f.Type(), ShouldEqual, test.typ)
+				}
+			})
+		}
+	})
+	Convey("Use with flag package", t, func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		verbose, size := false, 0
+		fs.Var(NewFlag(&verbose), "verbose", "")
+		fs.Var(NewFlag(&size), "size", "")
+		So(fs.Parse([]string{"-verbose", "-size", "2k"}), ShouldBeNil)
+		So(verbose, ShouldBeTrue)
+		So(size, ShouldEqual, 2000)
+		So(fs.Lookup("size").Value.(flag.Getter).Get(), ShouldEqual, 2000)
+	})
+}
+
+func TestSliceFlag(t *testing.T) {
+	Convey("Set slice flags", t, func() {
+		Convey("Typed slice replaces default, then appends", func() {
+			ints := []int{9}
+			f := NewSliceFlag(&ints)
+			So(f.String(), ShouldEqual, "[9]")
+			So(f.Set("1, 2k"), ShouldBeNil)
+			So(f.Set("3"), ShouldBeNil)
+			So(ints, ShouldResemble, []int{1, 2000, 3})
+			So(f.GetSlice(), ShouldResemble, []string{"1", "2000", "3"})
+			So(f.Type(), ShouldEqual, "intSlice")
+			So(f.Set("x"), ShouldNotBeNil)
+		})
+		Convey("Quoted strings", func() {
+			strs := []string{}
+			f := NewSliceFlag(&strs)
+			So(f.Set(`a,"b,c", d`), ShouldBeNil)
+			So(strs, ShouldResemble, []string{"a", "b,c", " d"})
+			So(f.Replace([]string{"x"}), ShouldBeNil)
+			So(f.Append("y"), ShouldBeNil)
+			So(strs, ShouldResemble, []string{"x", "y"})
+		})
+		Convey("Value", func() {
+			v := NewValue(nil)
+			f := NewSliceFlag(v)
+			So(f.Set("1,foo,true"), ShouldBeNil)
+			So(v.Interface(), ShouldResemble, []interface{}{int64(1), "foo", true})
+			So(f.Type(), ShouldEqual, "valueSlice")
+		})
+	})
+}
+
+func TestMapFlag(t *testing.T) {
+	Convey("Set map flags", t, func() {
+		Convey("Typed map", func() {
+			m := map[string]int{"default": 1}
+			f := NewMapFlag(&m)
+			So(f.Set("a=1,b=2k"), ShouldBeNil)
+			So(f.Set("c=3"), ShouldBeNil)
+			So(m, ShouldResemble, map[string]int{"a": 1, "b": 2000, "c": 3})
+			So(f.String(), ShouldEqual, "[a=1,b=2000,c=3]")
+			So(f.Type(), ShouldEqual, "stringToInt")
+			So(f.Set("d"), ShouldNotBeNil)
+			So(f.Set("d=x"), ShouldNotBeNil)
+		})
+		Convey("Value", func() {
+			v := NewValue(nil)
+			f := NewMapFlag(v)
+			So(f.Set("a=1,b=on"), ShouldBeNil)
+			So(v.Interface(), ShouldResemble, map[string]interface{}{"a": int64(1), "b": "on"})
+		})
+	})
+}
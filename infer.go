@@ -0,0 +1,147 @@
+package reflekt
+
+import (
+	"strconv"
+	"time"
+)
+
+// InferCategory is a kind of scalar which Infer can recognize in strings
+type InferCategory int
+
+const (
+	// InferNull recognizes null values as nil
+	InferNull InferCategory = iota
+
+	// InferInt recognizes decimal integers as int64
+	InferInt
+
+	// InferFloat recognizes floats as float64
+	InferFloat
+
+	// InferBool recognizes bools, as accepted by strconv.ParseBool, as bool
+	InferBool
+
+	// InferTime recognizes dates and times as time.Time
+	InferTime
+)
+
+var (
+	// DefaultInferOrder is the precedence of categories used by Infer
+	DefaultInferOrder = []InferCategory{InferNull, InferInt, InferFloat, InferBool, InferTime}
+
+	// DefaultInferNulls are the strings recognized as nil by Infer
+	DefaultInferNulls = []string{"null", "NULL", "Null", "nil"}
+
+	// DefaultInferTimeLayouts are the layouts used to recognize times by Infer
+	DefaultInferTimeLayouts = []string{
+		time.RFC3339Nano,
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+)
+
+// Inferrer determines the most specific Go value represented by a string
+type Inferrer struct {
+
+	// Order is the precedence of categories. Categories not contained are disabled
+	Order []InferCategory
+
+	// Nulls are the strings inferred as nil
+	Nulls []string
+
+	// TimeLayouts are tried in order to parse times
+	TimeLayouts []string
+}
+
+// NewInferrer creates an inferrer with the given category precedence, or the default if none
+// is given
+func NewInferrer(order ...InferCategory) *Inferrer {
+	if len(order) == 0 {
+		order = DefaultInferOrder
+	}
+	return &Inferrer{
+		Order:       append([]InferCategory{}, order...),
+		Nulls:       DefaultInferNulls,
+		TimeLayouts: DefaultInferTimeLayouts,
+	}
+}
+
+// Disable removes categories from the precedence
+func (this *Inferrer) Disable(categories ...InferCategory) *Inferrer {
+	order := []InferCategory{}
+	for _, c := range this.Order {
+		disabled := false
+		for _, d := range categories {
+			disabled = disabled || c == d
+		}
+		if !disabled {
+			order = append(order, c)
+		}
+	}
+	this.Order = order
+	return this
+}
+
+// Infer returns the value of the first category in the precedence which recognizes the string,
+// or the string itself
+func (this *Inferrer) Infer(s string) interface{} {
+	for _, c := range this.Order {
+		if v, ok := this.infer(c, s); ok {
+			return v
+		}
+	}
+	return s
+}
+
+func (this *Inferrer) infer(c InferCategory, s string) (interface{}, bool) {
+	switch c {
+	case InferNull:
+		for _, n := range this.Nulls {
+			if s == n {
+				return nil, true
+			}
+		}
+	case InferInt:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+	case InferFloat:
+		if f, ok := parseDecimal(s); ok {
+			return f, true
+		}
+	case InferBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, true
+		}
+	case InferTime:
+		for _, l := range this.TimeLayouts {
+			if t, err := time.Parse(l, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+var defaultInferrer = NewInferrer()
+
+// Infer returns the most specific Go value the string represents: nil, int64, float64, bool,
+// time.Time or the string itself, in that precedence
+func Infer(s string) interface{} {
+	return defaultInferrer.Infer(s)
+}
+
+// IsNumericString checks whether the string is a finite number in decimal or exponent syntax,
+// which AsInt and AsFloat parse as such
+func IsNumericString(s string) bool {
+	_, ok := parseDecimal(s)
+	return ok
+}
+
+// IsBoolString checks whether AsBool parses the string as a bool literal
+func IsBoolString(s string) bool {
+	_, err := parseBool(s)
+	return err == nil
+}
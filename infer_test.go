@@ -0,0 +1,73 @@
+package reflekt
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+var testsInfer = []struct {
+	from    string
+	to      interface{}
+	numeric bool
+	bool    bool
+}{
+	{from: "", to: ""},
+	{from: "foo", to: "foo"},
+	{from: "null", to: nil},
+	{from: "42", to: int64(42), numeric: true},
+	{from: "-42", to: int64(-42), numeric: true},
+	{from: "99999999999999999999", to: float64(99999999999999999999), numeric: true},
+	{from: "4.2", to: float64(4.2), numeric: true},
+	{from: "1e3", to: float64(1000), numeric: true},
+	{from: ".5", to: float64(0.5), numeric: true},
+	{from: "NaN", to: "NaN"},
+	{from: "Inf", to: "Inf"},
+	{from: "-infinity", to: "-infinity"},
+	{from: "1e400", to: "1e400"},
+	{from: "0x10", to: "0x10"},
+	{from: "1", to: int64(1), numeric: true, bool: true},
+	{from: "true", to: true, bool: true},
+	{from: "F", to: false, bool: true},
+	{from: "yes", to: "yes", bool: true},
+	{from: "2024-01-01", to: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	{from: "2024-01-01T10:11:12Z", to: time.Date(2024, 1, 1, 10, 11, 12, 0, time.UTC)},
+	{from: "2024-13-01", to: "2024-13-01"},
+}
+
+func TestInfer(t *testing.T) {
+	Convey("Infer scalars from strings", t, func() {
+		for i, test := range testsInfer {
+			Convey(fmt.Sprintf("%d) From %q expected %s (%v)", i, test.from, typeName(test.to), test.to), func() {
+				So(Infer(test.from), ShouldResemble, test.to)
+				So(IsNumericString(test.from), ShouldEqual, test.numeric)
+				So(IsBoolString(test.from), ShouldEqual, test.bool)
+			})
+		}
+	})
+}
+
+func TestInferrer(t *testing.T) {
+	Convey("Infer with custom precedence", t, func() {
+		i := NewInferrer(InferBool, InferInt)
+		So(i.Infer("1"), ShouldEqual, true)
+		So(i.Infer("2"), ShouldEqual, int64(2))
+		So(i.Infer("2.5"), ShouldEqual, "2.5")
+	})
+	Convey("Infer with disabled categories", t, func() {
+		i := NewInferrer().Disable(InferInt, InferNull)
+		So(i.Infer("1"), ShouldEqual, float64(1))
+		So(i.Infer("null"), ShouldEqual, "null")
+		So(i.Infer("2024-01-01"), ShouldResemble, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	})
+	Convey("Infer with custom nulls and layouts", t, func() {
+		i := NewInferrer()
+		i.Nulls = []string{"", "-"}
+		i.TimeLayouts = []string{"02.01.2006"}
+		So(i.Infer(""), ShouldBeNil)
+		So(i.Infer("-"), ShouldBeNil)
+		So(i.Infer("null"), ShouldEqual, "null")
+		So(i.Infer("31.12.2023"), ShouldResemble, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+	})
+}
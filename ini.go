@@ -0,0 +1,260 @@
+package reflekt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	iniEscaper   = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	iniUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+)
+
+// iniSection returns the map of the section path, creating missing sections
+func iniSection(res map[string]interface{}, path []string, line int) (map[string]interface{}, error) {
+	m := res
+	for _, seg := range path {
+		if seg == "" {
+			return nil, fmt.Errorf("Line %d: Empty section name", line)
+		}
+		sub, ok := m[seg].(map[string]interface{})
+		if !ok {
+			if _, exists := m[seg]; exists {
+				return nil, fmt.Errorf("Line %d: Section %s conflicts with key", line, strings.Join(path, "."))
+			}
+			sub = make(map[string]interface{})
+			m[seg] = sub
+		}
+		m = sub
+	}
+	return m, nil
+}
+
+// iniSectionPath parses the section header `[a.b]` or `[a "b"]` into its path
+func iniSectionPath(header string) []string {
+	header = strings.TrimSpace(header)
+	if p := strings.Index(header, " \""); p > 0 && strings.HasSuffix(header, "\"") {
+		path := iniSectionPath(header[:p])
+		return append(path, iniUnescaper.Replace(header[p+2:len(header)-1]))
+	}
+	path := strings.Split(header, ".")
+	for i := range path {
+		path[i] = strings.TrimSpace(path[i])
+	}
+	return path
+}
+
+// iniValue parses a quoted or unquoted value, removing inline comments from the latter
+func iniValue(s string, line int) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return s, nil
+	}
+	if q := s[0]; q == '"' || q == '\'' {
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if q == '"' && s[i] == '\\' {
+				i++
+			} else if s[i] == q {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return "", fmt.Errorf("Line %d: Unterminated quoted value", line)
+		} else if rest := strings.TrimSpace(s[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+			return "", fmt.Errorf("Line %d: Unexpected characters after quoted value", line)
+		}
+		if q == '\'' {
+			return s[1:end], nil
+		}
+		return iniUnescaper.Replace(s[1:end]), nil
+	}
+	for i := 1; i < len(s); i++ {
+		if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i]), nil
+		}
+	}
+	return s, nil
+}
+
+// ParseINI parses an INI file into nested maps of strings. Sections `[a]` become maps, subsections
+// `[a.b]` or `[a "b"]` nested maps. Lines starting with `;` or `#` are comments, as are inline
+// comments after whitespace in unquoted values. Values can be double quoted, with backslash
+// escapes, or single quoted. Keys given multiple times, or with a `[]` suffix, become slices.
+// Keys without `=` have an empty value.
+func ParseINI(data []byte) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+	section := res
+	lists := make(map[string]bool)
+	path := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		l := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			l = strings.TrimPrefix(l, "\ufeff")
+		}
+		if l == "" || l[0] == ';' || l[0] == '#' {
+			continue
+		} else if l[0] == '[' {
+			end := strings.LastIndex(l, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("Line %d: Unterminated section header", line)
+			}
+			p := iniSectionPath(l[1:end])
+			s, err := iniSection(res, p, line)
+			if err != nil {
+				return nil, err
+			}
+			section, path = s, strings.Join(p, ".")+"."
+			continue
+		}
+
+		key, raw := l, ""
+		if p := strings.IndexAny(l, "=:"); p >= 0 {
+			key, raw = strings.TrimSpace(l[:p]), l[p+1:]
+		}
+		list := strings.HasSuffix(key, "[]")
+		key = strings.TrimSpace(strings.TrimSuffix(key, "[]"))
+		if key == "" {
+			return nil, fmt.Errorf("Line %d: Missing key", line)
+		}
+		v, err := iniValue(raw, line)
+		if err != nil {
+			return nil, err
+		}
+
+		prev, exists := section[key]
+		if _, ok := prev.(map[string]interface{}); ok {
+			return nil, fmt.Errorf("Line %d: Key %s conflicts with section", line, key)
+		} else if lists[path+key] {
+			section[key] = append(prev.([]interface{}), v)
+		} else if exists {
+			lists[path+key] = true
+			section[key] = []interface{}{prev, v}
+		} else if list {
+			lists[path+key] = true
+			section[key] = []interface{}{v}
+		} else {
+			section[key] = v
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ReadINI reads an INI file into a Value. See ParseINI.
+func ReadINI(r io.Reader) (*Value, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseINI(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewValue(m), nil
+}
+
+// iniQuote returns the value quoted, if required to be parsed back as is
+func iniQuote(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\"';#=\\\n\r\t") {
+		return `"` + iniEscaper.Replace(s) + `"`
+	}
+	return s
+}
+
+// iniEntries returns the entries of the map or struct, converting structs with StructAsMap
+func iniEntries(v interface{}) (map[string]interface{}, bool) {
+	r := indirect(reflectValue(v))
+	if r.Kind() == reflect.Struct && isSectionValue(r) {
+		return StructAsMap(r.Interface(), true), true
+	} else if r.Kind() == reflect.Map {
+		return AsInterfaceMap(r.Interface()), true
+	}
+	return nil, false
+}
+
+func writeINISection(buf *bytes.Buffer, m map[string]interface{}, path []string) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sections := []string{}
+	written := false
+	for _, k := range keys {
+		if v, ok := m[k].(*Value); ok {
+			m[k] = v.Interface()
+		}
+		r := indirect(reflectValue(m[k]))
+		if _, ok := iniEntries(m[k]); ok {
+			sections = append(sections, k)
+			continue
+		} else if !r.IsValid() {
+			continue
+		}
+		if k == "" || k != strings.TrimSpace(k) || strings.ContainsAny(k, "=:[];#\"\r\n") {
+			return fmt.Errorf("Cannot encode key name %s", strconv.Quote(k))
+		}
+		if len(path) > 0 && !written {
+			if buf.Len() > 0 {
+				buf.WriteString("\n")
+			}
+			buf.WriteString("[" + strings.Join(path, ".") + "]\n")
+		}
+		written = true
+		if isListKind(r.Kind()) && r.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < r.Len(); i++ {
+				s, _, err := scalarText(r.Index(i))
+				if err != nil {
+					return fmt.Errorf("Cannot encode %s: %s", strings.Join(appendPath(path, k), "."), err)
+				}
+				buf.WriteString(k + "[] = " + iniQuote(s) + "\n")
+			}
+			continue
+		}
+		s, _, err := scalarText(r)
+		if err != nil {
+			return fmt.Errorf("Cannot encode %s: %s", strings.Join(appendPath(path, k), "."), err)
+		}
+		buf.WriteString(k + " = " + iniQuote(s) + "\n")
+	}
+
+	for _, k := range sections {
+		if strings.ContainsAny(k, ".[]\"\n") {
+			return fmt.Errorf("Cannot encode section name %s", strconv.Quote(k))
+		}
+		sub, _ := iniEntries(m[k])
+		if err := writeINISection(buf, sub, appendPath(path, k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalINI encodes the map or struct, converted with StructAsMap in snake case, as INI file.
+// Nested maps and structs become sections and subsections, slices repeated `key[]` entries. Keys
+// are sorted, keys of a section are written before its subsections. Nil values are omitted. Fails on
+// keys and section names which cannot be parsed back, like keys containing `=` or `;`.
+func MarshalINI(v interface{}) ([]byte, error) {
+	m, ok := iniEntries(v)
+	if !ok {
+		return nil, fmt.Errorf("Expected map or struct, got %T", v)
+	}
+	buf := new(bytes.Buffer)
+	if err := writeINISection(buf, m, []string{}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
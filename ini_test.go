@@ -0,0 +1,137 @@
+package reflekt
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tINIServer struct {
+	Host    string
+	Ports   []int
+	Started time.Time
+}
+
+type tINIConfig struct {
+	Name    string
+	Debug   bool
+	Motto   string
+	Server  tINIServer
+	Backup  *tINIServer
+	Options map[string]interface{}
+}
+
+func TestParseINI(t *testing.T) {
+	Convey("Parse INI file", t, func() {
+		m, err := ParseINI([]byte(`; global settings
+name = svc
+debug
+motto = "say \"hi\"; bye" ; comment
+path = /usr/bin # comment
+url: http://host/#anchor
+single = 'a;b'
+
+[server]
+host = localhost
+port = 80
+port = 81
+tags[] = one
+
+[server.tls]
+cert = /etc/cert
+
+[remote "origin"]
+url = git@host
+`))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{
+			"name":   "svc",
+			"debug":  "",
+			"motto":  `say "hi"; bye`,
+			"path":   "/usr/bin",
+			"url":    "http://host/#anchor",
+			"single": "a;b",
+			"server": map[string]interface{}{
+				"host": "localhost",
+				"port": []interface{}{"80", "81"},
+				"tags": []interface{}{"one"},
+				"tls":  map[string]interface{}{"cert": "/etc/cert"},
+			},
+			"remote": map[string]interface{}{
+				"origin": map[string]interface{}{"url": "git@host"},
+			},
+		})
+	})
+	Convey("Fill struct from INI", t, func() {
+		v, err := ReadINI(strings.NewReader("name = svc\ndebug = true\n[server]\nhost = localhost\nports[] = 80\n"))
+		So(err, ShouldBeNil)
+		c := &tINIConfig{}
+		So(NewStructFiller().Fill(c, v.InterfaceMap()), ShouldBeNil)
+		So(c.Name, ShouldEqual, "svc")
+		So(c.Debug, ShouldBeTrue)
+		So(c.Server.Host, ShouldEqual, "localhost")
+		So(c.Server.Ports, ShouldResemble, []int{80})
+	})
+	Convey("Fail on invalid files", t, func() {
+		for _, raw := range []string{"[server", "= x", `a = "x`, `a = "x" y`, "a = 1\n[a]", "[a]\n[b]\na=1\n[]", "[a]\n[b]\n[b.c]\nc=1\n[b]\nc=2"} {
+			_, err := ParseINI([]byte(raw))
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestMarshalINI(t *testing.T) {
+	Convey("Marshal struct", t, func() {
+		c := &tINIConfig{
+			Name:    "svc",
+			Debug:   true,
+			Motto:   `say "hi"; bye`,
+			Server:  tINIServer{Host: "localhost", Ports: []int{80, 81}, Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			Options: map[string]interface{}{"z": 1, "a": map[string]interface{}{"b": NewValue("c")}},
+		}
+		raw, err := MarshalINI(c)
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, `debug = true
+motto = "say \"hi\"; bye"
+name = svc
+
+[options]
+z = 1
+
+[options.a]
+b = c
+
+[server]
+host = localhost
+ports[] = 80
+ports[] = 81
+started = 2024-01-02T03:04:05Z
+`)
+
+		Convey("Round trip", func() {
+			m, err := ParseINI(raw)
+			So(err, ShouldBeNil)
+			to := &tINIConfig{}
+			So(NewStructFiller().Fill(to, m), ShouldBeNil)
+			So(to.Name, ShouldEqual, c.Name)
+			So(to.Motto, ShouldEqual, c.Motto)
+			So(to.Server, ShouldResemble, c.Server)
+			So(to.Options, ShouldResemble, map[string]interface{}{"z": "1", "a": map[string]interface{}{"b": "c"}})
+		})
+	})
+	Convey("Fail on unsupported values", t, func() {
+		_, err := MarshalINI("foo")
+		So(err, ShouldNotBeNil)
+		_, err = MarshalINI(map[string]interface{}{"a": []interface{}{map[string]int{}}})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Fail on invalid key names", t, func() {
+		for _, k := range []string{"[x", "a=b", ";c", "#d", "e:f", "g]", " h", "i\nj", ""} {
+			_, err := MarshalINI(map[string]interface{}{k: "y"})
+			So(err, ShouldNotBeNil)
+			_, err = MarshalINI(map[string]interface{}{"s": map[string]interface{}{k: []string{"y"}}})
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
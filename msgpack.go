@@ -0,0 +1,535 @@
+package reflekt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// MsgpackExt is a MessagePack extension value of an application specific type
+type MsgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// msgpackTimestamp is the extension type of timestamps
+const msgpackTimestamp = -1
+
+// MsgpackEncoder writes values as MessagePack to a stream
+type MsgpackEncoder struct {
+	w    *bufio.Writer
+	buf  [9]byte
+	seen map[walkRef]bool
+}
+
+// NewMsgpackEncoder creates an encoder writing to the writer
+func NewMsgpackEncoder(w io.Writer) *MsgpackEncoder {
+	return &MsgpackEncoder{
+		w:    bufio.NewWriter(w),
+		seen: make(map[walkRef]bool),
+	}
+}
+
+// Encode writes the value. Signed and unsigned integers, floats of both sizes, strings and []byte,
+// which is encoded as binary, keep their distinction. Maps are written with keys in the order of
+// Compare, structs as maps of their exported fields and times are written as timestamp extension.
+// Cyclic values cannot be encoded.
+func (this *MsgpackEncoder) Encode(v interface{}) error {
+	if err := this.encode(reflectValue(v)); err != nil {
+		return err
+	}
+	return this.w.Flush()
+}
+
+func (this *MsgpackEncoder) head(code byte, n uint64, size int) {
+	this.buf[0] = code
+	switch size {
+	case 1:
+		this.buf[1] = byte(n)
+	case 2:
+		binary.BigEndian.PutUint16(this.buf[1:], uint16(n))
+	case 4:
+		binary.BigEndian.PutUint32(this.buf[1:], uint32(n))
+	case 8:
+		binary.BigEndian.PutUint64(this.buf[1:], n)
+	}
+	this.w.Write(this.buf[:1+size])
+}
+
+// length writes the header of a string, binary, array or map of the length
+func (this *MsgpackEncoder) length(fix, fixMax, c8, c16, c32 byte, n int) error {
+	switch {
+	case fixMax > 0 && n <= int(fixMax):
+		this.w.WriteByte(fix | byte(n))
+	case c8 > 0 && n <= math.MaxUint8:
+		this.head(c8, uint64(n), 1)
+	case n <= math.MaxUint16:
+		this.head(c16, uint64(n), 2)
+	case uint64(n) <= math.MaxUint32:
+		this.head(c32, uint64(n), 4)
+	default:
+		return fmt.Errorf("Length %d exceeds MessagePack limits", n)
+	}
+	return nil
+}
+
+func (this *MsgpackEncoder) encodeInt(i int64) {
+	switch {
+	case i >= 0:
+		this.encodeUint(uint64(i))
+	case i >= -32:
+		this.w.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		this.head(0xd0, uint64(i), 1)
+	case i >= math.MinInt16:
+		this.head(0xd1, uint64(i), 2)
+	case i >= math.MinInt32:
+		this.head(0xd2, uint64(i), 4)
+	default:
+		this.head(0xd3, uint64(i), 8)
+	}
+}
+
+func (this *MsgpackEncoder) encodeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		this.w.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		this.head(0xcc, u, 1)
+	case u <= math.MaxUint16:
+		this.head(0xcd, u, 2)
+	case u <= math.MaxUint32:
+		this.head(0xce, u, 4)
+	default:
+		this.head(0xcf, u, 8)
+	}
+}
+
+func (this *MsgpackEncoder) encodeExt(typ int8, data []byte) error {
+	switch len(data) {
+	case 1, 2, 4, 8, 16:
+		codes := map[int]byte{1: 0xd4, 2: 0xd5, 4: 0xd6, 8: 0xd7, 16: 0xd8}
+		this.w.WriteByte(codes[len(data)])
+	default:
+		if err := this.length(0, 0, 0xc7, 0xc8, 0xc9, len(data)); err != nil {
+			return err
+		}
+	}
+	this.w.WriteByte(byte(typ))
+	this.w.Write(data)
+	return nil
+}
+
+func (this *MsgpackEncoder) encodeTime(t time.Time) error {
+	sec, nsec := uint64(t.Unix()), uint64(t.Nanosecond())
+	if sec>>34 == 0 {
+		data := nsec<<34 | sec
+		if data&0xffffffff00000000 == 0 {
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, uint32(data))
+			return this.encodeExt(msgpackTimestamp, b)
+		}
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, data)
+		return this.encodeExt(msgpackTimestamp, b)
+	}
+	b := make([]byte, 12)
+	binary.BigEndian.PutUint32(b, uint32(nsec))
+	binary.BigEndian.PutUint64(b[4:], sec)
+	return this.encodeExt(msgpackTimestamp, b)
+}
+
+// enter marks the pointer, map or slice as on the current path, failing if it already is
+func (this *MsgpackEncoder) enter(r reflect.Value) (walkRef, error) {
+	ref := walkRef{r.Pointer(), 0, r.Type()}
+	if r.Kind() == reflect.Slice {
+		ref.l = r.Len()
+	}
+	if this.seen[ref] {
+		return ref, fmt.Errorf("Cannot encode cyclic %s as MessagePack", r.Type())
+	}
+	this.seen[ref] = true
+	return ref, nil
+}
+
+func (this *MsgpackEncoder) encode(r reflect.Value) error {
+	for r.Kind() == reflect.Ptr || r.Kind() == reflect.Interface {
+		if r.IsNil() {
+			return this.w.WriteByte(0xc0)
+		} else if r.Kind() == reflect.Ptr {
+			ref, err := this.enter(r)
+			if err != nil {
+				return err
+			}
+			defer delete(this.seen, ref)
+		}
+		r = r.Elem()
+	}
+	if !r.IsValid() {
+		return this.w.WriteByte(0xc0)
+	} else if !r.CanInterface() {
+		return fmt.Errorf("Cannot encode unexported %s", r.Type())
+	}
+	switch v := r.Interface().(type) {
+	case time.Time:
+		return this.encodeTime(v)
+	case Value:
+		return this.encode(reflect.ValueOf(v.v))
+	case MsgpackExt:
+		return this.encodeExt(v.Type, v.Data)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			this.encodeInt(i)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		this.head(0xcb, math.Float64bits(f), 8)
+		return nil
+	}
+
+	k := r.Kind()
+	switch {
+	case k == reflect.Bool:
+		if r.Bool() {
+			return this.w.WriteByte(0xc3)
+		}
+		return this.w.WriteByte(0xc2)
+	case IsIntKind(k):
+		this.encodeInt(r.Int())
+	case IsUintKind(k):
+		this.encodeUint(r.Uint())
+	case k == reflect.Float32:
+		this.head(0xca, uint64(math.Float32bits(float32(r.Float()))), 4)
+	case k == reflect.Float64:
+		this.head(0xcb, math.Float64bits(r.Float()), 8)
+	case k == reflect.String:
+		if err := this.length(0xa0, 31, 0xd9, 0xda, 0xdb, r.Len()); err != nil {
+			return err
+		}
+		this.w.WriteString(r.String())
+	case isListKind(k) && r.Type().Elem().Kind() == reflect.Uint8:
+		if err := this.length(0, 0, 0xc4, 0xc5, 0xc6, r.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < r.Len(); i++ {
+			this.w.WriteByte(byte(r.Index(i).Uint()))
+		}
+	case isListKind(k):
+		if k == reflect.Slice {
+			ref, err := this.enter(r)
+			if err != nil {
+				return err
+			}
+			defer delete(this.seen, ref)
+		}
+		if err := this.length(0x90, 15, 0, 0xdc, 0xdd, r.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < r.Len(); i++ {
+			if err := this.encode(r.Index(i)); err != nil {
+				return err
+			}
+		}
+	case k == reflect.Map:
+		ref, err := this.enter(r)
+		if err != nil {
+			return err
+		}
+		defer delete(this.seen, ref)
+		keys := r.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return Compare(keys[i], keys[j]) < 0
+		})
+		if err := this.length(0x80, 15, 0, 0xde, 0xdf, len(keys)); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := this.encode(key); err != nil {
+				return err
+			} else if err := this.encode(r.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case k == reflect.Struct:
+		return this.encode(reflect.ValueOf(structFields(r)))
+	default:
+		return fmt.Errorf("Cannot encode %s as MessagePack", r.Type())
+	}
+	return nil
+}
+
+// MsgpackDecoder reads MessagePack values from a stream
+type MsgpackDecoder struct {
+	r      *bufio.Reader
+	filler *StructFiller
+	depth  int
+}
+
+// NewMsgpackDecoder creates a decoder reading from the reader
+func NewMsgpackDecoder(r io.Reader) *MsgpackDecoder {
+	return &MsgpackDecoder{
+		r:      bufio.NewReader(r),
+		filler: NewStructFiller(),
+	}
+}
+
+// UseFiller sets the StructFiller used to decode into structs
+func (this *MsgpackDecoder) UseFiller(filler *StructFiller) *MsgpackDecoder {
+	this.filler = filler
+	return this
+}
+
+// Decode reads the next value into the pointer, which can point to a Value, an interface{}, a
+// struct, which is filled with the StructFiller of the decoder, or any other type the decoded
+// value can be cast to. Integers are decoded as int64, or uint64 if too large, floats as float32
+// or float64, binary as []byte, maps with only string keys as map[string]interface{} and other
+// maps as map[interface{}]interface{}, timestamps as time.Time in UTC and other extensions as
+// MsgpackExt. Arrays and maps can be nested at most 10000 levels deep. Returns io.EOF if the
+// stream is exhausted.
+func (this *MsgpackDecoder) Decode(dst interface{}) error {
+	v, err := this.decode()
+	if err != nil {
+		return err
+	}
+	return this.filler.decodeInto(v, dst)
+}
+
+func (this *MsgpackDecoder) read(n uint64) ([]byte, error) {
+	if n <= 1<<16 {
+		b := make([]byte, n)
+		_, err := io.ReadFull(this.r, b)
+		return b, this.unexpected(err)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(this.r, int64(n)))
+	if err == nil && uint64(len(b)) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+// unexpected converts io.EOF within a value into io.ErrUnexpectedEOF
+func (this *MsgpackDecoder) unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (this *MsgpackDecoder) uint(size int) (uint64, error) {
+	b, err := this.read(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (this *MsgpackDecoder) decode() (interface{}, error) {
+	c, err := this.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	v, err := this.decodeCode(c)
+	return v, this.unexpected(err)
+}
+
+func (this *MsgpackDecoder) decodeCode(c byte) (interface{}, error) {
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c >= 0x80 && c <= 0x8f:
+		return this.decodeMap(uint64(c & 0x0f))
+	case c >= 0x90 && c <= 0x9f:
+		return this.decodeArray(uint64(c & 0x0f))
+	case c >= 0xa0 && c <= 0xbf:
+		b, err := this.read(uint64(c & 0x1f))
+		return string(b), err
+	}
+
+	sizes := map[byte]int{
+		0xc4: 1, 0xc5: 2, 0xc6: 4, 0xc7: 1, 0xc8: 2, 0xc9: 4,
+		0xcc: 1, 0xcd: 2, 0xce: 4, 0xcf: 8, 0xd0: 1, 0xd1: 2, 0xd2: 4, 0xd3: 8,
+		0xd9: 1, 0xda: 2, 0xdb: 4, 0xdc: 2, 0xdd: 4, 0xde: 2, 0xdf: 4,
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		n, err := this.uint(4)
+		return math.Float32frombits(uint32(n)), err
+	case 0xcb:
+		n, err := this.uint(8)
+		return math.Float64frombits(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return this.decodeExt(uint64(1) << (c - 0xd4))
+	}
+	size, ok := sizes[c]
+	if !ok {
+		return nil, fmt.Errorf("Invalid MessagePack code 0x%02x", c)
+	}
+	n, err := this.uint(size)
+	if err != nil {
+		return nil, err
+	}
+	switch c {
+	case 0xc4, 0xc5, 0xc6:
+		return this.read(n)
+	case 0xc7, 0xc8, 0xc9:
+		return this.decodeExt(n)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0:
+		return int64(int8(n)), nil
+	case 0xd1:
+		return int64(int16(n)), nil
+	case 0xd2:
+		return int64(int32(n)), nil
+	case 0xd3:
+		return int64(n), nil
+	case 0xd9, 0xda, 0xdb:
+		b, err := this.read(n)
+		return string(b), err
+	case 0xdc, 0xdd:
+		return this.decodeArray(n)
+	}
+	return this.decodeMap(n)
+}
+
+func (this *MsgpackDecoder) decodeExt(n uint64) (interface{}, error) {
+	typ, err := this.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := this.read(n)
+	if err != nil {
+		return nil, err
+	} else if int8(typ) != msgpackTimestamp {
+		return MsgpackExt{int8(typ), data}, nil
+	}
+	switch len(data) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&0x3ffffffff), int64(v>>34)).UTC(), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data)
+		return time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(nsec)).UTC(), nil
+	}
+	return nil, fmt.Errorf("Invalid MessagePack timestamp of length %d", len(data))
+}
+
+func (this *MsgpackDecoder) decodeArray(n uint64) (interface{}, error) {
+	if err := this.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { this.depth-- }()
+	res := make([]interface{}, 0, minUint(n, 1024))
+	for i := uint64(0); i < n; i++ {
+		v, err := this.decode()
+		if err != nil {
+			return nil, this.unexpected(err)
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+func (this *MsgpackDecoder) decodeMap(n uint64) (interface{}, error) {
+	if err := this.nest(); err != nil {
+		return nil, err
+	}
+	defer func() { this.depth-- }()
+	res := make(map[interface{}]interface{}, minUint(n, 1024))
+	strs := true
+	for i := uint64(0); i < n; i++ {
+		k, err := this.decode()
+		if err != nil {
+			return nil, this.unexpected(err)
+		}
+		v, err := this.decode()
+		if err != nil {
+			return nil, this.unexpected(err)
+		}
+		if kr := reflect.ValueOf(k); kr.IsValid() && !kr.Type().Comparable() {
+			return nil, fmt.Errorf("Unsupported MessagePack map key of type %T", k)
+		}
+		_, ok := k.(string)
+		strs = strs && ok
+		res[k] = v
+	}
+	if !strs {
+		return res, nil
+	}
+	m := make(map[string]interface{}, len(res))
+	for k, v := range res {
+		m[k.(string)] = v
+	}
+	return m, nil
+}
+
+// nest enters an array or map, failing beyond maxDecodeDepth
+func (this *MsgpackDecoder) nest() error {
+	if this.depth >= maxDecodeDepth {
+		return fmt.Errorf("MessagePack data exceeds maximum nesting depth of %d", maxDecodeDepth)
+	}
+	this.depth++
+	return nil
+}
+
+// maxDecodeDepth is the maximum nesting of arrays and maps accepted by decoders
+const maxDecodeDepth = 10000
+
+func minUint(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// MarshalMsgpack encodes the value as MessagePack. See MsgpackEncoder.Encode.
+func MarshalMsgpack(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := NewMsgpackEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalMsgpack decodes MessagePack into the pointer. See MsgpackDecoder.Decode.
+func UnmarshalMsgpack(data []byte, dst interface{}) error {
+	d := NewMsgpackDecoder(bytes.NewReader(data))
+	if err := d.Decode(dst); err != nil {
+		return d.unexpected(err)
+	} else if _, err := d.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("Unexpected data after MessagePack value")
+	}
+	return nil
+}
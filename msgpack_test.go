@@ -0,0 +1,181 @@
+package reflekt
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+type tMsgpackInner struct {
+	Data []byte
+	At   time.Time
+}
+
+type tMsgpackOuter struct {
+	Name  string
+	Count int
+	Ratio float32
+	Inner tMsgpackInner
+	Tags  []string
+	Attrs map[string]interface{}
+}
+
+type tMsgpackNode struct {
+	Next *tMsgpackNode
+}
+
+var testsMsgpackEncode = []struct {
+	from   interface{}
+	expect []byte
+}{
+	{nil, []byte{0xc0}},
+	{true, []byte{0xc3}},
+	{false, []byte{0xc2}},
+	{0, []byte{0x00}},
+	{127, []byte{0x7f}},
+	{128, []byte{0xcc, 0x80}},
+	{-1, []byte{0xff}},
+	{-33, []byte{0xd0, 0xdf}},
+	{int64(-40000), []byte{0xd2, 0xff, 0xff, 0x63, 0xc0}},
+	{uint16(256), []byte{0xcd, 0x01, 0x00}},
+	{uint64(math.MaxUint64), []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	{float32(1.5), []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}},
+	{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	{"foo", []byte{0xa3, 'f', 'o', 'o'}},
+	{[]byte("ab"), []byte{0xc4, 0x02, 'a', 'b'}},
+	{[]int{1, 2}, []byte{0x92, 0x01, 0x02}},
+	{map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02}},
+	{time.Unix(1, 0), []byte{0xd6, 0xff, 0, 0, 0, 1}},
+	{MsgpackExt{5, []byte{1, 2, 3}}, []byte{0xc7, 0x03, 0x05, 1, 2, 3}},
+	{NewValue("x"), []byte{0xa1, 'x'}},
+}
+
+func TestMarshalMsgpack(t *testing.T) {
+	Convey("Encode values", t, func() {
+		for _, test := range testsMsgpackEncode {
+			res, err := MarshalMsgpack(test.from)
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, test.expect)
+		}
+	})
+	Convey("Encode long values", t, func() {
+		res, err := MarshalMsgpack(string(make([]byte, 300)))
+		So(err, ShouldBeNil)
+		So(res[:3], ShouldResemble, []byte{0xda, 0x01, 0x2c})
+		res, err = MarshalMsgpack(make([]interface{}, 20))
+		So(err, ShouldBeNil)
+		So(res[:3], ShouldResemble, []byte{0xdc, 0x00, 0x14})
+	})
+	Convey("Fail on unsupported values", t, func() {
+		_, err := MarshalMsgpack(map[string]interface{}{"f": func() {}})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Fail on cyclic values", t, func() {
+		m := map[string]interface{}{}
+		m["self"] = m
+		_, err := MarshalMsgpack(m)
+		So(err, ShouldNotBeNil)
+		s := []interface{}{nil}
+		s[0] = s
+		_, err = MarshalMsgpack(s)
+		So(err, ShouldNotBeNil)
+		node := &tMsgpackNode{}
+		node.Next = node
+		_, err = MarshalMsgpack(node)
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Encode shared values", t, func() {
+		node := &tMsgpackNode{}
+		inner := []int{1}
+		res, err := MarshalMsgpack([]interface{}{node, node, inner, inner})
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, []byte{0x94, 0x81, 0xa4, 'N', 'e', 'x', 't', 0xc0, 0x81, 0xa4, 'N', 'e', 'x', 't', 0xc0, 0x91, 0x01, 0x91, 0x01})
+	})
+}
+
+func TestUnmarshalMsgpack(t *testing.T) {
+	Convey("Round trip preserves types", t, func() {
+		from := map[string]interface{}{
+			"int":   int64(-5),
+			"big":   uint64(math.MaxUint64),
+			"f32":   float32(0.25),
+			"f64":   0.1,
+			"bin":   []byte{0, 1},
+			"str":   "foo",
+			"nil":   nil,
+			"list":  []interface{}{true, int64(1)},
+			"ints":  map[interface{}]interface{}{int64(1): "a"},
+			"time":  time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			"early": time.Date(1900, 1, 2, 3, 4, 5, 6, time.UTC),
+			"ext":   MsgpackExt{3, []byte{1, 2}},
+		}
+		data, err := MarshalMsgpack(from)
+		So(err, ShouldBeNil)
+		var to interface{}
+		So(UnmarshalMsgpack(data, &to), ShouldBeNil)
+		So(to, ShouldResemble, from)
+	})
+	Convey("Decode into Value", t, func() {
+		data, _ := MarshalMsgpack(map[string]interface{}{"a": map[string]interface{}{"b": 1}})
+		v := NewValue(nil)
+		So(UnmarshalMsgpack(data, v), ShouldBeNil)
+		So(v.Get("a.b").Int(), ShouldEqual, 1)
+	})
+	Convey("Decode into struct", t, func() {
+		from := tMsgpackOuter{
+			Name:  "foo",
+			Count: 3,
+			Ratio: 0.5,
+			Inner: tMsgpackInner{Data: []byte("x"), At: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			Tags:  []string{"a"},
+			Attrs: map[string]interface{}{"k": int64(1)},
+		}
+		data, err := MarshalMsgpack(&from)
+		So(err, ShouldBeNil)
+		to := tMsgpackOuter{}
+		So(UnmarshalMsgpack(data, &to), ShouldBeNil)
+		So(to, ShouldResemble, from)
+	})
+	Convey("Decode into scalar", t, func() {
+		data, _ := MarshalMsgpack(42)
+		var i int
+		So(UnmarshalMsgpack(data, &i), ShouldBeNil)
+		So(i, ShouldEqual, 42)
+	})
+	Convey("Fail on invalid data", t, func() {
+		var v interface{}
+		So(UnmarshalMsgpack([]byte{0xc1}, &v), ShouldNotBeNil)
+		So(UnmarshalMsgpack([]byte{0xa3, 'f'}, &v), ShouldEqual, io.ErrUnexpectedEOF)
+		So(UnmarshalMsgpack([]byte{0x92, 0x01}, &v), ShouldEqual, io.ErrUnexpectedEOF)
+		So(UnmarshalMsgpack([]byte{}, &v), ShouldEqual, io.ErrUnexpectedEOF)
+		So(UnmarshalMsgpack([]byte{0x01, 0x02}, &v), ShouldNotBeNil)
+		So(UnmarshalMsgpack([]byte{0x01}, v), ShouldNotBeNil)
+	})
+	Convey("Fail on too deeply nested data", t, func() {
+		var v interface{}
+		So(UnmarshalMsgpack(append(bytes.Repeat([]byte{0x91}, maxDecodeDepth), 0x01), &v), ShouldBeNil)
+		So(UnmarshalMsgpack(append(bytes.Repeat([]byte{0x91}, maxDecodeDepth+1), 0x01), &v), ShouldNotBeNil)
+		So(UnmarshalMsgpack(bytes.Repeat([]byte{0x81, 0x01}, 1000000), &v), ShouldNotBeNil)
+	})
+}
+
+func TestMsgpackStream(t *testing.T) {
+	Convey("Encode and decode multiple values", t, func() {
+		buf := new(bytes.Buffer)
+		enc := NewMsgpackEncoder(buf)
+		So(enc.Encode("foo"), ShouldBeNil)
+		So(enc.Encode(map[string]int{"a": 1}), ShouldBeNil)
+
+		dec := NewMsgpackDecoder(buf)
+		var s string
+		So(dec.Decode(&s), ShouldBeNil)
+		So(s, ShouldEqual, "foo")
+		v := NewValue(nil)
+		So(dec.Decode(v), ShouldBeNil)
+		So(v.Interface(), ShouldResemble, map[string]interface{}{"a": int64(1)})
+		So(dec.Decode(v), ShouldEqual, io.EOF)
+	})
+}
@@ -0,0 +1,250 @@
+package reflekt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// propertiesLines returns the logical lines of a properties file, with continuations joined and
+// comments and blank lines removed
+func propertiesLines(data string) []string {
+	res := []string{}
+	cont := false
+	for _, l := range strings.Split(strings.Replace(data, "\r\n", "\n", -1), "\n") {
+		l = strings.TrimRight(l, "\r")
+		trimmed := strings.TrimLeft(l, " \t\f")
+		if !cont && (trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!') {
+			continue
+		}
+		backslashes := 0
+		for i := len(trimmed) - 1; i >= 0 && trimmed[i] == '\\'; i-- {
+			backslashes++
+		}
+		next := backslashes%2 == 1
+		if next {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if cont {
+			res[len(res)-1] += trimmed
+		} else {
+			res = append(res, trimmed)
+		}
+		cont = next
+	}
+	return res
+}
+
+// propertiesUnescape resolves backslash escapes, including `\uXXXX` and surrogate pairs of them
+func propertiesUnescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	res := []uint16{}
+	add := func(r rune) {
+		res = append(res, utf16.Encode([]rune{r})...)
+	}
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		if rs[i] != '\\' || i+1 >= len(rs) {
+			add(rs[i])
+			continue
+		}
+		i++
+		switch rs[i] {
+		case 't':
+			add('\t')
+		case 'n':
+			add('\n')
+		case 'r':
+			add('\r')
+		case 'f':
+			add('\f')
+		case 'u':
+			if i+4 >= len(rs) {
+				return "", fmt.Errorf("Malformed \\uXXXX escape in %q", s)
+			}
+			c, err := strconv.ParseUint(string(rs[i+1:i+5]), 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("Malformed \\uXXXX escape in %q", s)
+			}
+			res = append(res, uint16(c))
+			i += 4
+		default:
+			add(rs[i])
+		}
+	}
+	return string(utf16.Decode(res)), nil
+}
+
+// propertiesSplit splits a logical line into key and value at the first unescaped `=`, `:` or
+// whitespace
+func propertiesSplit(l string) (string, string) {
+	for i := 0; i < len(l); i++ {
+		switch l[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return l[:i], strings.TrimLeft(l[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			v := strings.TrimLeft(l[i:], " \t\f")
+			if v != "" && (v[0] == '=' || v[0] == ':') {
+				v = strings.TrimLeft(v[1:], " \t\f")
+			}
+			return l[:i], v
+		}
+	}
+	return l, ""
+}
+
+// ParseProperties parses a Java properties file into nested maps of strings, by splitting keys at
+// dots. Keys and values are separated by `=`, `:` or whitespace, lines starting with `#` or `!` are
+// comments and lines ending with a backslash are continued. Backslash escapes, including `\uXXXX`,
+// are resolved. Nested maps with the keys 0 to n-1 become slices. A key which has a value and
+// nested keys, like `a=1` and `a.b=2`, becomes a map with the value under the empty key.
+func ParseProperties(data []byte) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+	for _, l := range propertiesLines(string(data)) {
+		rk, rv := propertiesSplit(l)
+		k, err := propertiesUnescape(rk)
+		if err != nil {
+			return nil, err
+		}
+		v, err := propertiesUnescape(rv)
+		if err != nil {
+			return nil, err
+		}
+		path := strings.Split(k, ".")
+		m := res
+		for _, seg := range path[:len(path)-1] {
+			sub, ok := m[seg].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				if prev, exists := m[seg]; exists {
+					sub[""] = prev
+				}
+				m[seg] = sub
+			}
+			m = sub
+		}
+		last := path[len(path)-1]
+		if sub, ok := m[last].(map[string]interface{}); ok {
+			sub[""] = v
+		} else {
+			m[last] = v
+		}
+	}
+	return indexedMaps(res), nil
+}
+
+// ReadProperties reads a Java properties file into a Value. See ParseProperties.
+func ReadProperties(r io.Reader) (*Value, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseProperties(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewValue(m), nil
+}
+
+// propertiesEscape escapes the string as key or value, with non-ASCII characters as `\uXXXX`
+func propertiesEscape(s string, key bool) string {
+	buf := new(bytes.Buffer)
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			buf.WriteString(`\ `)
+		case (r == '=' || r == ':') && key, (r == '#' || r == '!') && i == 0:
+			buf.WriteRune('\\')
+			buf.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(buf, `\u%04x`, u)
+			}
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// flattenText collects the dotted keys of the map, struct or slice and their values as text
+func flattenText(r reflect.Value, prefix string, res map[string]string) error {
+	r = indirect(r)
+	if r.IsValid() && r.Type() == valueType {
+		r = indirect(reflect.ValueOf(r.Interface().(Value).v))
+	}
+	switch {
+	case !r.IsValid():
+		return nil
+	case r.Kind() == reflect.Struct && isSectionValue(r):
+		r = reflect.ValueOf(StructAsMap(r.Interface(), true))
+		fallthrough
+	case r.Kind() == reflect.Map:
+		for _, k := range r.MapKeys() {
+			p := prefix + AsString(k) + "."
+			if AsString(k) == "" {
+				p = prefix
+			}
+			if err := flattenText(r.MapIndex(k), p, res); err != nil {
+				return err
+			}
+		}
+	case isListKind(r.Kind()) && r.Type().Elem().Kind() != reflect.Uint8:
+		for i := 0; i < r.Len(); i++ {
+			if err := flattenText(r.Index(i), prefix+strconv.Itoa(i)+".", res); err != nil {
+				return err
+			}
+		}
+	default:
+		s, _, err := scalarText(r)
+		if err != nil {
+			return fmt.Errorf("Cannot encode %s: %s", strings.TrimSuffix(prefix, "."), err)
+		}
+		res[strings.TrimSuffix(prefix, ".")] = s
+	}
+	return nil
+}
+
+// MarshalProperties encodes the map or struct, converted with StructAsMap in snake case, as Java
+// properties file. Nested maps and structs become dotted keys, slices keys with indices. Keys are
+// sorted, nil values omitted.
+func MarshalProperties(v interface{}) ([]byte, error) {
+	r := indirect(reflectValue(v))
+	if r.Kind() != reflect.Map && !(r.Kind() == reflect.Struct && isSectionValue(r)) {
+		return nil, fmt.Errorf("Expected map or struct, got %T", v)
+	}
+	entries := make(map[string]string)
+	if err := flattenText(r, "", entries); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf := new(bytes.Buffer)
+	for _, k := range keys {
+		buf.WriteString(propertiesEscape(k, true) + "=" + propertiesEscape(entries[k], false) + "\n")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package reflekt
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	Convey("Parse properties file", t, func() {
+		v, err := ReadProperties(strings.NewReader(`# comment
+! other comment
+server.host = localhost
+server.port:8080
+server.name   my server
+greeting = Hello, \
+           World
+path=C:\\temp
+unicode=caf\u00e9 \ud83d\ude00
+key\ with\ spaces = x
+escaped\=key = y
+tabs=a\tb
+empty
+hosts.0=a
+hosts.1=b
+`))
+		So(err, ShouldBeNil)
+		So(v.Interface(), ShouldResemble, map[string]interface{}{
+			"server": map[string]interface{}{
+				"host": "localhost",
+				"port": "8080",
+				"name": "my server",
+			},
+			"greeting":        "Hello, World",
+			"path":            `C:\temp`,
+			"unicode":         "café 😀",
+			"key with spaces": "x",
+			"escaped=key":     "y",
+			"tabs":            "a\tb",
+			"empty":           "",
+			"hosts":           []interface{}{"a", "b"},
+		})
+		So(v.Get("server.port").Int(), ShouldEqual, 8080)
+		So(v.Get("hosts.1").String(), ShouldEqual, "b")
+		So(v.Get("server.missing").Interface(), ShouldBeNil)
+	})
+	Convey("Parse keys with values and nested keys", t, func() {
+		m, err := ParseProperties([]byte("log4j.appender.A1=Console\nlog4j.appender.A1.layout=x\nb.c=1\nb=2"))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{
+			"log4j": map[string]interface{}{"appender": map[string]interface{}{"A1": map[string]interface{}{"": "Console", "layout": "x"}}},
+			"b":     map[string]interface{}{"": "2", "c": "1"},
+		})
+		raw, err := MarshalProperties(m)
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, "b=2\nb.c=1\nlog4j.appender.A1=Console\nlog4j.appender.A1.layout=x\n")
+	})
+	Convey("Parse numeric keys", t, func() {
+		m, err := ParseProperties([]byte("0=a\n1=b"))
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{"0": "a", "1": "b"})
+	})
+	Convey("Fail on invalid files", t, func() {
+		for _, raw := range []string{`a=\u12`, `a=\uxyzw`} {
+			_, err := ParseProperties([]byte(raw))
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestMarshalProperties(t *testing.T) {
+	Convey("Marshal nested map", t, func() {
+		from := map[string]interface{}{
+			"server":   map[string]interface{}{"host": "localhost", "port": 8080},
+			"greeting": " Hello,\n World",
+			"unicode":  "café 😀",
+			"key: =":   "#x",
+			"hosts":    []string{"a", "b"},
+			"nil":      nil,
+		}
+		raw, err := MarshalProperties(from)
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, `greeting=\ Hello,\n World
+hosts.0=a
+hosts.1=b
+key\:\ \==\#x
+server.host=localhost
+server.port=8080
+unicode=caf\u00e9 \ud83d\ude00
+`)
+
+		Convey("Round trip", func() {
+			m, err := ParseProperties(raw)
+			So(err, ShouldBeNil)
+			So(m, ShouldResemble, map[string]interface{}{
+				"server":   map[string]interface{}{"host": "localhost", "port": "8080"},
+				"greeting": " Hello,\n World",
+				"unicode":  "café 😀",
+				"key: =":   "#x",
+				"hosts":    []interface{}{"a", "b"},
+			})
+		})
+	})
+	Convey("Marshal struct", t, func() {
+		raw, err := MarshalProperties(&tINIConfig{Name: "svc", Server: tINIServer{Host: "h", Ports: []int{1}}})
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldContainSubstring, "name=svc\n")
+		So(string(raw), ShouldContainSubstring, "server.ports.0=1\n")
+	})
+	Convey("Fail on unsupported values", t, func() {
+		_, err := MarshalProperties(1)
+		So(err, ShouldNotBeNil)
+		_, err = MarshalProperties(map[string]interface{}{"f": func() {}})
+		So(err, ShouldNotBeNil)
+	})
+}
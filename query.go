@@ -0,0 +1,181 @@
+package reflekt
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// queryPath splits a query key in bracket or dotted syntax, eg `a[b][]` or `a.b`, into its path.
+// An empty last segment denotes a list.
+func queryPath(key string) ([]string, error) {
+	path := []string{}
+	seg := ""
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; c {
+		case '.':
+			path = append(path, seg)
+			seg = ""
+		case '[':
+			end := strings.IndexByte(key[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated bracket in key %s", key)
+			}
+			if i > 0 && key[i-1] != ']' {
+				path = append(path, seg)
+			}
+			path = append(path, key[i+1:i+end])
+			seg = ""
+			i += end
+			if i+1 < len(key) && key[i+1] != '[' && key[i+1] != '.' {
+				return nil, fmt.Errorf("Unexpected characters after bracket in key %s", key)
+			} else if i+1 < len(key) && key[i+1] == '.' {
+				i++
+			}
+		default:
+			seg += string(c)
+		}
+	}
+	if len(key) == 0 || key[len(key)-1] != ']' {
+		path = append(path, seg)
+	}
+	for i, p := range path {
+		if p == "" && (i == 0 || i < len(path)-1) {
+			return nil, fmt.Errorf("Unsupported empty segment in key %s", key)
+		}
+	}
+	return path, nil
+}
+
+// ParseQuery decodes query or form values into nested maps. Keys in bracket syntax, eg
+// `filter[status]`, or dotted syntax, eg `filter.status`, become nested maps, keys ending with
+// `[]` lists and maps with the keys 0 to n-1, eg from `items[0][name]`, lists as well. Keys with
+// multiple values become lists of strings, all other values strings.
+func ParseQuery(values url.Values) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make(map[string]interface{})
+	for _, k := range keys {
+		path, err := queryPath(k)
+		if err != nil {
+			return nil, err
+		}
+		list := path[len(path)-1] == ""
+		if list {
+			path = path[:len(path)-1]
+		}
+		m := res
+		for i, seg := range path[:len(path)-1] {
+			sub, ok := m[seg].(map[string]interface{})
+			if !ok {
+				if _, exists := m[seg]; exists {
+					return nil, fmt.Errorf("Key %s conflicts with %s", k, strings.Join(path[:i+1], "."))
+				}
+				sub = make(map[string]interface{})
+				m[seg] = sub
+			}
+			m = sub
+		}
+		last := path[len(path)-1]
+		prev, exists := m[last]
+		if _, ok := prev.(map[string]interface{}); ok {
+			return nil, fmt.Errorf("Key %s conflicts with nested keys", k)
+		}
+		vs := make([]interface{}, len(values[k]))
+		for i, v := range values[k] {
+			vs[i] = v
+		}
+		if l, ok := prev.([]interface{}); ok && exists {
+			m[last] = append(l, vs...)
+		} else if exists {
+			m[last] = append([]interface{}{prev}, vs...)
+		} else if list || len(vs) > 1 {
+			m[last] = vs
+		} else if len(vs) == 1 {
+			m[last] = vs[0]
+		}
+	}
+	return indexedMaps(res), nil
+}
+
+// queryEncode adds the value at the key in bracket syntax to the values
+func queryEncode(r reflect.Value, key string, res url.Values) error {
+	r = indirect(r)
+	if r.IsValid() && r.Type() == valueType {
+		r = indirect(reflect.ValueOf(r.Interface().(Value).v))
+	}
+	switch {
+	case !r.IsValid():
+		return nil
+	case r.Kind() == reflect.Struct && isSectionValue(r):
+		r = reflect.ValueOf(StructAsMap(r.Interface(), true))
+		fallthrough
+	case r.Kind() == reflect.Map:
+		for _, k := range r.MapKeys() {
+			sub := AsString(k)
+			if key != "" {
+				sub = key + "[" + sub + "]"
+			}
+			if err := queryEncode(r.MapIndex(k), sub, res); err != nil {
+				return err
+			}
+		}
+	case isListKind(r.Kind()) && r.Type().Elem().Kind() != reflect.Uint8:
+		for i := 0; i < r.Len(); i++ {
+			e := indirect(r.Index(i))
+			if e.IsValid() && (e.Kind() == reflect.Map || isListKind(e.Kind()) || (e.Kind() == reflect.Struct && isSectionValue(e))) {
+				if err := queryEncode(e, key+"["+strconv.Itoa(i)+"]", res); err != nil {
+					return err
+				}
+			} else if s, ok, err := scalarText(e); err != nil {
+				return fmt.Errorf("Cannot encode %s: %s", key, err)
+			} else if ok {
+				res.Add(key+"[]", s)
+			}
+		}
+	default:
+		s, _, err := scalarText(r)
+		if err != nil {
+			return fmt.Errorf("Cannot encode %s: %s", key, err)
+		}
+		res.Add(key, s)
+	}
+	return nil
+}
+
+// EncodeQuery encodes the map or struct, converted with StructAsMap in snake case, into query
+// values in bracket syntax: nested maps and structs as `a[b]`, lists of scalars as repeated `a[]`
+// and other lists with indices, as `a[0][b]`. Nil values are omitted.
+func EncodeQuery(v interface{}) (url.Values, error) {
+	r := indirect(reflectValue(v))
+	if r.Kind() != reflect.Map && !(r.Kind() == reflect.Struct && isSectionValue(r)) {
+		return nil, fmt.Errorf("Expected map or struct, got %T", v)
+	}
+	res := url.Values{}
+	if err := queryEncode(r, "", res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// FillQuery fills the struct from query or form values, decoded with ParseQuery
+func (this *StructFiller) FillQuery(s interface{}, values url.Values) error {
+	d, err := ParseQuery(values)
+	if err != nil {
+		return err
+	}
+	return this.Fill(s, d)
+}
+
+// FillQuery fills the struct from query or form values, using a StructFiller which reads keys
+// from the `query` tag. See ParseQuery.
+func FillQuery(s interface{}, values url.Values) error {
+	return NewStructFiller().UseTag("query").FillQuery(s, values)
+}
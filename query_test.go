@@ -0,0 +1,125 @@
+package reflekt
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/url"
+	"testing"
+)
+
+var testsQueryPath = []struct {
+	key  string
+	path []string
+	err  bool
+}{
+	{key: "a", path: []string{"a"}},
+	{key: "a[b][c]", path: []string{"a", "b", "c"}},
+	{key: "a[]", path: []string{"a", ""}},
+	{key: "a.b.c", path: []string{"a", "b", "c"}},
+	{key: "a.b[c]", path: []string{"a", "b", "c"}},
+	{key: "a[0].name", path: []string{"a", "0", "name"}},
+	{key: "a[b", err: true},
+	{key: "a[b]c", err: true},
+	{key: "a[][b]", err: true},
+	{key: "a..b", err: true},
+	{key: "", err: true},
+}
+
+func TestQueryPath(t *testing.T) {
+	Convey("Split query keys", t, func() {
+		for i, test := range testsQueryPath {
+			Convey(fmt.Sprintf("(%d) Split %q", i+1, test.key), func() {
+				path, err := queryPath(test.key)
+				if test.err {
+					So(err, ShouldNotBeNil)
+				} else {
+					So(err, ShouldBeNil)
+					So(path, ShouldResemble, test.path)
+				}
+			})
+		}
+	})
+}
+
+type tQueryPage struct {
+	Size   int
+	Number int
+}
+
+type tQuery struct {
+	Filter map[string]string
+	Tags   []string
+	IDs    []int `query:"id"`
+	Page   tQueryPage
+	Items  []struct{ Name string }
+	Active bool
+}
+
+func TestParseQuery(t *testing.T) {
+	Convey("Decode bracket and dotted syntax", t, func() {
+		values, _ := url.ParseQuery("filter[status]=open&tags[]=a&tags[]=b&page[size]=10&page.number=2&items[0][name]=x&items[1].name=y&multi=1&multi=2&plain=foo")
+		m, err := ParseQuery(values)
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{
+			"filter": map[string]interface{}{"status": "open"},
+			"tags":   []interface{}{"a", "b"},
+			"page":   map[string]interface{}{"size": "10", "number": "2"},
+			"items":  []interface{}{map[string]interface{}{"name": "x"}, map[string]interface{}{"name": "y"}},
+			"multi":  []interface{}{"1", "2"},
+			"plain":  "foo",
+		})
+	})
+	Convey("Keep numeric keys at the root", t, func() {
+		m, err := ParseQuery(url.Values{"0": {"x"}, "1": {"y"}})
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{"0": "x", "1": "y"})
+	})
+	Convey("Fail on conflicts", t, func() {
+		for _, q := range []string{"a=1&a[b]=2", "a[b]=1&a=2", "a[b=1"} {
+			values, _ := url.ParseQuery(q)
+			_, err := ParseQuery(values)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestEncodeQuery(t *testing.T) {
+	Convey("Encode nested values", t, func() {
+		values, err := EncodeQuery(map[string]interface{}{
+			"filter": map[string]interface{}{"status": "open"},
+			"tags":   []string{"a", "b"},
+			"items":  []interface{}{map[string]interface{}{"name": "x"}},
+			"page":   &tQueryPage{Size: 10},
+			"nil":    nil,
+		})
+		So(err, ShouldBeNil)
+		So(values.Encode(), ShouldEqual, "filter%5Bstatus%5D=open&items%5B0%5D%5Bname%5D=x&page%5Bnumber%5D=0&page%5Bsize%5D=10&tags%5B%5D=a&tags%5B%5D=b")
+
+		Convey("Round trip", func() {
+			m, err := ParseQuery(values)
+			So(err, ShouldBeNil)
+			So(m["items"], ShouldResemble, []interface{}{map[string]interface{}{"name": "x"}})
+			So(m["tags"], ShouldResemble, []interface{}{"a", "b"})
+		})
+	})
+	Convey("Fail on unsupported values", t, func() {
+		_, err := EncodeQuery("x")
+		So(err, ShouldNotBeNil)
+		_, err = EncodeQuery(map[string]interface{}{"f": func() {}})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestFillQuery(t *testing.T) {
+	Convey("Fill struct from query", t, func() {
+		values, _ := url.ParseQuery("filter[status]=open&tags[]=a&id=1&page[size]=10&items[0][name]=x&active=1")
+		q := &tQuery{Page: tQueryPage{Number: 1}}
+		So(FillQuery(q, values), ShouldBeNil)
+		So(q.Filter, ShouldResemble, map[string]string{"status": "open"})
+		So(q.Tags, ShouldResemble, []string{"a"})
+		So(q.IDs, ShouldResemble, []int{1})
+		So(q.Page, ShouldResemble, tQueryPage{Size: 10, Number: 1})
+		So(q.Items[0].Name, ShouldEqual, "x")
+		So(q.Active, ShouldBeTrue)
+	})
+}
@@ -2,10 +2,74 @@ package reflekt
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// number suffixes accepted by the casters and their multipliers
+var numberSuffixes = []struct {
+	suffix string
+	mult   float64
+}{
+	{"ki", 1 << 10}, {"mi", 1 << 20}, {"gi", 1 << 30}, {"ti", 1 << 40},
+	{"k", 1e3}, {"m", 1e6}, {"g", 1e9}, {"t", 1e12},
+}
+
+// castValue returns the value the casters work with: the underlying value of driver.Valuer
+// implementations and []byte as string, see sqlValue, and big integers as int64 or, if too large,
+// as decimal string
+func castValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *big.Int:
+		if x == nil {
+			return nil
+		} else if x.IsInt64() {
+			return x.Int64()
+		}
+		return x.String()
+	case reflect.Value:
+		if x.IsValid() && x.CanInterface() {
+			if i, ok := x.Interface().(*big.Int); ok {
+				return castValue(i)
+			}
+		}
+	}
+	return sqlValue(v)
+}
+
+// parseBool parses the literals accepted by strconv.ParseBool and the words "yes", "y" and "on"
+// as true and "no", "n" and "off" as false, case insensitive
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(s))
+}
+
+// parseNumber parses numbers as strconv.ParseFloat does, and numbers with the decimal suffixes k,
+// m, g and t or the binary suffixes ki, mi, gi and ti, case insensitive. "1.5k" is 1500.
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	l := strings.ToLower(s)
+	for _, n := range numberSuffixes {
+		if strings.HasSuffix(l, n.suffix) {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(l[:len(l)-len(n.suffix)]), 64); err == nil {
+				return f * n.mult, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("Cannot parse %q as number", s)
+}
+
 // IsIntKind checks if provided kind is of any unsigned integer kind
 func IsUintKind(k reflect.Kind) bool {
 	return k == reflect.Uint || k == reflect.Uint8 || k == reflect.Uint16 || k == reflect.Uint32 || k == reflect.Uint64
@@ -47,6 +111,113 @@ func IsFloat(v interface{}) bool {
 	return IsFloatKind(k)
 }
 
+// IsNumericKind checks if provided kind is of any integer or float kind
+func IsNumericKind(k reflect.Kind) bool {
+	return IsIntKind(k) || IsUintKind(k) || IsFloatKind(k)
+}
+
+// IsNumeric checks if value is of any integer or float kind
+func IsNumeric(v interface{}) bool {
+	return IsNumericKind(reflectValue(v).Kind())
+}
+
+// IsScalar checks if value is of bool, string or any numeric kind
+func IsScalar(v interface{}) bool {
+	k := reflectValue(v).Kind()
+	return k == reflect.Bool || k == reflect.String || IsNumericKind(k)
+}
+
+// IsCollection checks if value is a map, slice or array
+func IsCollection(v interface{}) bool {
+	k := reflectValue(v).Kind()
+	return k == reflect.Map || k == reflect.Slice || k == reflect.Array
+}
+
+// IsMapLike checks if value is a map or a pointer to a map
+func IsMapLike(v interface{}) bool {
+	return indirect(reflectValue(v)).Kind() == reflect.Map
+}
+
+// IsStructLike checks if value is a struct or a pointer to a struct
+func IsStructLike(v interface{}) bool {
+	return indirect(reflectValue(v)).Kind() == reflect.Struct
+}
+
+// IsEmpty checks if value is nil, a nil pointer, an empty string, an empty collection or the zero
+// value of its type
+func IsEmpty(v interface{}) bool {
+	r := reflectValue(v)
+	switch r.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Map, reflect.Slice, reflect.Chan:
+		return r.Len() == 0
+	case reflect.Array:
+		return r.Len() == 0 || r.IsZero()
+	default:
+		return r.IsZero()
+	}
+}
+
+// IsZero checks if value is deeply the zero value: all pointers are nil or point to zero values,
+// all elements of collections and all fields of structs are zero values
+func IsZero(v interface{}) bool {
+	return isZero(reflectValue(v), make(map[walkRef]bool))
+}
+
+func isZero(r reflect.Value, seen map[walkRef]bool) bool {
+	switch r.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		if r.IsNil() {
+			return true
+		} else if r.Kind() == reflect.Ptr {
+			ref := walkRef{r.Pointer(), 0, r.Type()}
+			if seen[ref] {
+				return true
+			}
+			seen[ref] = true
+		}
+		return isZero(r.Elem(), seen)
+	case reflect.Map:
+		ref := walkRef{r.Pointer(), 0, r.Type()}
+		if seen[ref] {
+			return true
+		}
+		seen[ref] = true
+		for _, k := range r.MapKeys() {
+			if !isZero(r.MapIndex(k), seen) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if r.Kind() == reflect.Slice && r.Len() > 0 {
+			ref := walkRef{r.Pointer(), r.Len(), r.Type()}
+			if seen[ref] {
+				return true
+			}
+			seen[ref] = true
+		}
+		for i := 0; i < r.Len(); i++ {
+			if !isZero(r.Index(i), seen) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < r.NumField(); i++ {
+			if !isZero(r.Field(i), seen) {
+				return false
+			}
+		}
+		return true
+	default:
+		return r.IsZero()
+	}
+}
+
 // AsInterfaces returns value as array of interfaces. If value is not a slice, then the returned result
 // will have the length of 1
 func AsInterfaces(v interface{}) []interface{} {
@@ -65,6 +236,7 @@ func AsInterfaces(v interface{}) []interface{} {
 
 // AsInt tries to return or convert the value from anything to int
 func AsInt(v interface{}) int {
+	v = castValue(v)
 	if v == nil {
 		return 0
 	}
@@ -90,8 +262,8 @@ func AsInt(v interface{}) int {
 		}
 	case k == reflect.String:
 		if i, e := strconv.ParseInt(r.String(), 10, 0); e != nil {
-			if f, e := strconv.ParseFloat(r.String(), 64); e != nil {
-				if b, _ := strconv.ParseBool(r.String()); b {
+			if f, e := parseNumber(r.String()); e != nil {
+				if b, _ := parseBool(r.String()); b {
 					return 1
 				} else {
 					return 0
@@ -121,6 +293,7 @@ func AsInts(v interface{}) []int {
 
 // AsFloat tries to return or convert the value from anything to float64
 func AsFloat(v interface{}) float64 {
+	v = castValue(v)
 	if v == nil {
 		return float64(0)
 	}
@@ -134,8 +307,8 @@ func AsFloat(v interface{}) float64 {
 	case IsFloatKind(k):
 		return r.Float()
 	case k == reflect.String:
-		if f, e := strconv.ParseFloat(r.String(), 64); e != nil {
-			if b, _ := strconv.ParseBool(r.String()); b {
+		if f, e := parseNumber(r.String()); e != nil {
+			if b, _ := parseBool(r.String()); b {
 				return float64(1)
 			} else {
 				return float64(0)
@@ -162,6 +335,7 @@ func AsFloats(v interface{}) []float64 {
 
 // AsBool tries to return or convert the value from anything to bool
 func AsBool(v interface{}) bool {
+	v = castValue(v)
 	if v == nil {
 		return false
 	}
@@ -175,7 +349,7 @@ func AsBool(v interface{}) bool {
 	case r.Kind() == reflect.Bool:
 		return r.Bool()
 	case k == reflect.String:
-		if b, e := strconv.ParseBool(r.String()); e != nil {
+		if b, e := parseBool(r.String()); e != nil {
 			return AsFloat(v) > 0
 		} else {
 			return b
@@ -199,6 +373,7 @@ func AsBools(v interface{}) []bool {
 
 // AsString tries to return or convert the value from anything to string
 func AsString(v interface{}) string {
+	v = castValue(v)
 	if v == nil {
 		return ""
 	}
@@ -213,6 +388,8 @@ func AsString(v interface{}) string {
 		return r.String()
 	case k == reflect.Interface:
 		return fmt.Sprintf("%v", r.Interface())
+	case k == reflect.Struct && r.Type() == timeType && r.CanInterface():
+		return r.Interface().(time.Time).Format(time.RFC3339Nano)
 	case r.Kind() == reflect.Bool:
 		return fmt.Sprintf("%v", r.Bool())
 	case IsInt(v):
@@ -3,6 +3,7 @@ package reflekt
 import (
 	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"math/big"
 	"reflect"
 	"sort"
 	"strings"
@@ -195,6 +196,24 @@ var testsInt = []struct {
 		to:        0,
 		toSlice:   []int{1, 2, 3},
 	},
+	{
+		from:      "1.5k",
+		recognize: false,
+		to:        1500,
+		toSlice:   []int{1500},
+	},
+	{
+		from:      "yes",
+		recognize: false,
+		to:        1,
+		toSlice:   []int{1},
+	},
+	{
+		from:      big.NewInt(123),
+		recognize: false,
+		to:        123,
+		toSlice:   []int{123},
+	},
 }
 
 func TestIsInt(t *testing.T) {
@@ -382,6 +401,12 @@ var testsFloat = []struct {
 		to:        0,
 		toSlice:   []float64{1, 2, 3},
 	},
+	{
+		from:      "2Ki",
+		recognize: false,
+		to:        float64(2048),
+		toSlice:   []float64{2048},
+	},
 }
 
 func TestIsFloat(t *testing.T) {
@@ -534,6 +559,16 @@ var testsBool = []struct {
 		to:      false,
 		toSlice: []bool{true, false, true},
 	},
+	{
+		from:    "yes",
+		to:      true,
+		toSlice: []bool{true},
+	},
+	{
+		from:    "Off",
+		to:      false,
+		toSlice: []bool{false},
+	},
 }
 
 func TestAsBool(t *testing.T) {
@@ -636,6 +671,11 @@ var testsString = []struct {
 		to:      "",
 		toSlice: []string{"foo", "bar"},
 	},
+	{
+		from:    new(big.Int).Lsh(big.NewInt(1), 64),
+		to:      "18446744073709551616",
+		toSlice: []string{"18446744073709551616"},
+	},
 }
 
 func TestAsString(t *testing.T) {
@@ -885,3 +925,65 @@ func typeName(v interface{}) string {
 		return reflect.TypeOf(v).String()
 	}
 }
+
+var testsClassify = []struct {
+	from       interface{}
+	numeric    bool
+	scalar     bool
+	collection bool
+	mapLike    bool
+	structLike bool
+	empty      bool
+	zero       bool
+}{
+	{from: nil, empty: true, zero: true},
+	{from: 0, numeric: true, scalar: true, empty: true, zero: true},
+	{from: uint8(1), numeric: true, scalar: true},
+	{from: 1.5, numeric: true, scalar: true},
+	{from: reflect.ValueOf(1.5), numeric: true, scalar: true},
+	{from: "", scalar: true, empty: true, zero: true},
+	{from: "foo", scalar: true},
+	{from: false, scalar: true, empty: true, zero: true},
+	{from: []int{}, collection: true, empty: true, zero: true},
+	{from: []int{0, 0}, collection: true, zero: true},
+	{from: []int{0, 1}, collection: true},
+	{from: [2]int{}, collection: true, empty: true, zero: true},
+	{from: [2]int{0, 1}, collection: true},
+	{from: map[string]int{}, collection: true, mapLike: true, empty: true, zero: true},
+	{from: map[string]interface{}{"foo": nil}, collection: true, mapLike: true, zero: true},
+	{from: &map[string]int{"foo": 1}, mapLike: true},
+	{from: struct{ A int }{}, structLike: true, empty: true, zero: true},
+	{from: struct{ A *int }{A: new(int)}, structLike: true, zero: true},
+	{from: &struct{ A int }{A: 1}, structLike: true},
+	{from: (*int)(nil), empty: true, zero: true},
+	{from: reflect.ValueOf([]string{}), collection: true, empty: true, zero: true},
+}
+
+func TestIsZeroCyclic(t *testing.T) {
+	Convey("Check cyclic collections", t, func() {
+		m := map[string]interface{}{}
+		m["self"] = m
+		So(IsZero(m), ShouldBeTrue)
+		m["foo"] = 1
+		So(IsZero(m), ShouldBeFalse)
+		l := []interface{}{nil}
+		l[0] = l
+		So(IsZero(l), ShouldBeTrue)
+	})
+}
+
+func TestClassify(t *testing.T) {
+	Convey("Classify values", t, func() {
+		for i, test := range testsClassify {
+			Convey(fmt.Sprintf("%d) From %s (%v)", i, typeName(test.from), test.from), func() {
+				So(IsNumeric(test.from), ShouldEqual, test.numeric)
+				So(IsScalar(test.from), ShouldEqual, test.scalar)
+				So(IsCollection(test.from), ShouldEqual, test.collection)
+				So(IsMapLike(test.from), ShouldEqual, test.mapLike)
+				So(IsStructLike(test.from), ShouldEqual, test.structLike)
+				So(IsEmpty(test.from), ShouldEqual, test.empty)
+				So(IsZero(test.from), ShouldEqual, test.zero)
+			})
+		}
+	})
+}
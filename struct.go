@@ -1,7 +1,10 @@
 package reflekt
 
 import (
+	"database/sql"
+	"encoding"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 )
@@ -11,7 +14,12 @@ func structElemAs(f reflect.Value, lc bool, m map[string]interface{}) interface{
 		f = f.Elem()
 	}
 	switch f.Kind() {
+	case reflect.Invalid:
+		return nil
 	case reflect.Struct:
+		if f.Type() == timeType {
+			return f.Interface()
+		}
 		return structAsMap(f.Interface(), lc, m)
 	case reflect.Slice:
 		s := make([]interface{}, f.Len())
@@ -66,12 +74,23 @@ func StructAsMap(v interface{}, snakeCase ...bool) map[string]interface{} {
 	return structAsMap(v, len(snakeCase) > 0 && snakeCase[0], nil)
 }
 
-// StructFiller is going to be an awesome tool to simply fill structs from maps (thereby JSON and all that).. just not yet
+// structFields returns the exported fields of the struct by name, with embedded structs inlined
+func structFields(r reflect.Value) map[string]interface{} {
+	res := make(map[string]interface{})
+	for name, fv := range valueEntries(r, false, nil) {
+		res[name] = fv.Interface()
+	}
+	return res
+}
+
+// StructFiller fills structs from maps (thereby JSON and all that), casting values leniently to
+// the field types. Fields are matched by tag (see UseTag), name, lower case name or snake case name.
 type StructFiller struct {
-	m map[reflect.Type]func(v interface{}) reflect.Type
+	m   map[reflect.Type]func(v interface{}) reflect.Type
+	tag string
 }
 
-// NewStructFiller generates new filler.. DONT USE IT, YET
+// NewStructFiller generates new filler
 func NewStructFiller() *StructFiller {
 	return &StructFiller{
 		m: make(map[reflect.Type]func(v interface{}) reflect.Type),
@@ -84,130 +103,181 @@ func (this *StructFiller) Register(iface reflect.Type, determine func(v interfac
 	return this
 }
 
+// UseTag sets the name of the struct tag which contains the key a field is filled from. A tag
+// value of `-` excludes the field.
+func (this *StructFiller) UseTag(tag string) *StructFiller {
+	this.tag = tag
+	return this
+}
+
+// fieldNames returns the keys a field can be filled from, in order of precedence
+func (this *StructFiller) fieldNames(ft reflect.StructField) []string {
+	names := []string{}
+	if this.tag != "" {
+		if t := strings.Split(ft.Tag.Get(this.tag), ",")[0]; t == "-" {
+			return names
+		} else if t != "" {
+			names = append(names, t)
+		}
+	}
+	return append(names, ft.Name, strings.ToLower(ft.Name), snakeCase(ft.Name))
+}
+
+// setScalar casts and assigns v to fv, which must be of bool, string or any numeric kind
+func setScalar(fv reflect.Value, v interface{}) {
+	fk := fv.Kind()
+	if IsIntKind(fk) {
+		fv.SetInt(int64(AsInt(v)))
+	} else if IsUintKind(fk) {
+		fv.SetUint(uint64(AsInt(v)))
+	} else if IsFloatKind(fk) {
+		fv.SetFloat(AsFloat(v))
+	} else if fk == reflect.Bool {
+		fv.SetBool(AsBool(v))
+	} else if fk == reflect.String {
+		fv.SetString(AsString(v))
+	}
+}
+
 func (this *StructFiller) set(fv reflect.Value, ft reflect.StructField, d map[string]interface{}, p, prefix string) error {
 	if !fv.CanSet() {
-		fmt.Printf("- Cannot set %s\n", ft.Name)
 		return nil
 	}
-	for _, n := range []string{ft.Name, strings.ToLower(ft.Name)} {
+	for _, n := range this.fieldNames(ft) {
 		if v, ok := d[n]; ok {
-			fk := fv.Kind()
-			vv := reflect.ValueOf(v)
-			if IsIntKind(fk) {
-				fv.SetInt(int64(AsInt(v)))
-			} else if IsFloatKind(fk) {
-				fv.SetFloat(AsFloat(v))
-			} else if fk == reflect.Bool {
-				fv.SetBool(AsBool(v))
-			} else if fk == reflect.String {
-				fv.SetString(AsString(v))
-			} else if fk == reflect.Struct {
-				if ft.Anonymous {
-					// TODO: ..
-				} else if vv.Kind() == reflect.Map {
-					sub := reflect.New(fv.Type())
-					this.fill(sub.Interface(), AsInterfaceMap(v), p+n+":")
-					fv.Set(sub.Elem())
-				} else {
-					return fmt.Errorf(prefix+"Cannot fill sub-struct %s (%s) from %s", n, fk, vv.Kind())
-				}
-			} else if fk == reflect.Ptr {
-				if vv.Kind() == reflect.Map {
-					sub := reflect.New(fv.Type().Elem())
-					this.fill(sub.Interface(), AsInterfaceMap(v), p+n+":")
-					fv.Set(sub)
-				} else {
-					return fmt.Errorf(prefix+"Cannot fill sub-struct ptr %s (%s) from %s", n, fk, vv.Kind())
-				}
-			} else if fk == reflect.Interface {
-				if cast, ok := this.m[fv.Type()]; !ok {
-					return fmt.Errorf(prefix+"Not found registererd cast for interface %s for %s", fv.Kind(), n)
-				} else if vv.Kind() == reflect.Map {
-					sub := reflect.New(cast(v))
-					this.fill(sub.Interface(), AsInterfaceMap(v), p+n+":")
-					fv.Set(sub)
-				} else {
-					return fmt.Errorf(prefix+"Cannot fill sub-struct ptr %s (%s) from %s", n, fk, vv.Kind())
-				}
-			} else if fk == reflect.Slice {
-				if vv.Kind() != reflect.Slice {
-					return fmt.Errorf(prefix+"Cannot fill slice %s (%s) from %s", n, fk, vv.Kind())
-				}
-				pt := ft.Type.Elem()
-				var et reflect.Type
-				if pt.Kind() == reflect.Interface {
-					if cast, ok := this.m[pt]; !ok {
-						return fmt.Errorf(prefix+"Not found registererd cast for interface %s for slice %s", fv.Kind(), n)
-					} else {
-						et = cast(v)
-					}
-				} else {
-					et = pt
-					for et.Kind() == reflect.Ptr {
-						et = et.Elem()
-					}
-				}
-				st := reflect.SliceOf(et)
-				l := fv.Len()
-				reflect.MakeSlice(st, l, l)
-				if et.Kind() == reflect.Struct {
-					if vv.Kind() != reflect.Map {
-						return fmt.Errorf(prefix+"Cannot fill slice of %s with for slice %s", et, vv.Kind(), n)
-					} else {
-						for i := 0; i < l; i++ {
-							e := reflect.New(et)
-							this.fill(e.Interface(), AsInterfaceMap(v), fmt.Sprintf("%s%s.%d:", p, n, i))
-							fv.Field(i).Set(e)
-						}
-					}
-				} else {
-					for i := 0; i < l; i++ {
-						e := reflect.New(et)
-						fv.Field(i).Set(e)
-					}
-				}
-
-				//s := reflect.MakeSlice()
-				//fv.Set(vv)
-			} else if fk == vv.Kind() {
-				fv.Set(vv)
-			} else {
-				return fmt.Errorf(prefix+"Cannot fill %s (%s) from %s", n, fk, vv.Kind())
-			}
-			return nil
+			return this.assign(fv, v, n, p, prefix)
 		}
 	}
 
 	return nil
 }
 
+// assign casts and assigns v to fv, which is filled from key n
+func (this *StructFiller) assign(fv reflect.Value, v interface{}, n, p, prefix string) error {
+	fk := fv.Kind()
+	vv := reflect.ValueOf(v)
+	if scanner, ok := fv.Addr().Interface().(sql.Scanner); ok {
+		if err := scanner.Scan(castValue(v)); err != nil {
+			return fmt.Errorf(prefix+"Cannot scan %s: %s", n, err)
+		}
+	} else if !vv.IsValid() {
+		fv.Set(reflect.Zero(fv.Type()))
+	} else if vv.Type().AssignableTo(fv.Type()) {
+		fv.Set(vv)
+	} else if IsScalar(fv) {
+		setScalar(fv, v)
+	} else if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok && vv.Kind() == reflect.String {
+		if vv.String() == "" {
+			fv.Set(reflect.Zero(fv.Type()))
+		} else if err := u.UnmarshalText([]byte(vv.String())); err != nil {
+			return fmt.Errorf(prefix+"Cannot parse %s: %s", n, err)
+		}
+	} else if fv.Type() == bigIntType || fv.Type() == reflect.PtrTo(bigIntType) {
+		i, ok := asBigInt(v)
+		if !ok {
+			return fmt.Errorf(prefix+"Cannot fill %s (%s) from %s", n, fv.Type(), vv.Kind())
+		} else if fk == reflect.Ptr {
+			fv.Set(reflect.ValueOf(i))
+		} else {
+			fv.Set(reflect.ValueOf(i).Elem())
+		}
+	} else if fk == reflect.Struct {
+		if vv.Kind() == reflect.Map {
+			sub := reflect.New(fv.Type())
+			sub.Elem().Set(fv)
+			if err := this.fill(sub.Interface(), AsInterfaceMap(v), p+n+":"); err != nil {
+				return err
+			}
+			fv.Set(sub.Elem())
+		} else {
+			return fmt.Errorf(prefix+"Cannot fill sub-struct %s (%s) from %s", n, fk, vv.Kind())
+		}
+	} else if fk == reflect.Ptr {
+		if vv.Kind() == reflect.Map {
+			sub := reflect.New(fv.Type().Elem())
+			if !fv.IsNil() {
+				sub.Elem().Set(fv.Elem())
+			}
+			if err := this.fill(sub.Interface(), AsInterfaceMap(v), p+n+":"); err != nil {
+				return err
+			}
+			fv.Set(sub)
+		} else if sub := reflect.New(fv.Type().Elem()); IsScalar(sub.Elem()) {
+			setScalar(sub.Elem(), v)
+			fv.Set(sub)
+		} else {
+			return fmt.Errorf(prefix+"Cannot fill sub-struct ptr %s (%s) from %s", n, fk, vv.Kind())
+		}
+	} else if fk == reflect.Interface {
+		if cast, ok := this.m[fv.Type()]; !ok {
+			return fmt.Errorf(prefix+"Not found registererd cast for interface %s for %s", fv.Kind(), n)
+		} else if vv.Kind() == reflect.Map {
+			sub := reflect.New(cast(v))
+			this.fill(sub.Interface(), AsInterfaceMap(v), p+n+":")
+			fv.Set(sub)
+		} else {
+			return fmt.Errorf(prefix+"Cannot fill sub-struct ptr %s (%s) from %s", n, fk, vv.Kind())
+		}
+	} else if fk == reflect.Slice {
+		if vv.Kind() != reflect.Slice && vv.Kind() != reflect.Array {
+			vv = reflect.ValueOf([]interface{}{v})
+		}
+		s := reflect.MakeSlice(fv.Type(), vv.Len(), vv.Len())
+		for i := 0; i < vv.Len(); i++ {
+			if err := this.assign(s.Index(i), vv.Index(i).Interface(), fmt.Sprintf("%s.%d", n, i), p, prefix); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+	} else if fk == reflect.Map && vv.Kind() == reflect.Map {
+		m := reflect.MakeMapWithSize(fv.Type(), vv.Len())
+		for _, k := range vv.MapKeys() {
+			mk, mv := reflect.New(fv.Type().Key()).Elem(), reflect.New(fv.Type().Elem()).Elem()
+			if err := this.assign(mk, k.Interface(), n, p, prefix); err != nil {
+				return err
+			} else if err := this.assign(mv, vv.MapIndex(k).Interface(), fmt.Sprintf("%s.%v", n, k), p, prefix); err != nil {
+				return err
+			}
+			m.SetMapIndex(mk, mv)
+		}
+		fv.Set(m)
+	} else if fk == vv.Kind() {
+		fv.Set(vv)
+	} else {
+		return fmt.Errorf(prefix+"Cannot fill %s (%s) from %s", n, fk, vv.Kind())
+	}
+	return nil
+}
+
 func (this *StructFiller) fill(s interface{}, d map[string]interface{}, p string) error {
 	r := reflect.ValueOf(s)
 	prefix := p
 	if prefix != "" {
 		prefix = prefix + " "
 	}
-	fmt.Printf("Starting with %s (%s)\n", r.Kind(), prefix)
 	w := []string{}
 	for r.Kind() == reflect.Ptr || r.Kind() == reflect.Interface {
 		w = append(w, r.Kind().String())
 		r = r.Elem()
-		fmt.Printf(" > Hopping to %s\n", r.Kind())
 	}
-	fmt.Printf("Using now %s (%s)\n", r.Kind(), prefix)
 	if r.Kind() != reflect.Struct {
 		return fmt.Errorf(prefix+"Expected (ptr|interface)+ -> struct, got %s -> %s", strings.Join(w, " -> "), r.Kind())
 	}
-	t := r.Type()
-	if r.Kind() == reflect.Interface {
-		t = r.Elem().Type()
-	}
+	return this.fillStruct(r, d, p, prefix)
+}
 
+func (this *StructFiller) fillStruct(r reflect.Value, d map[string]interface{}, p, prefix string) error {
+	t := r.Type()
 	for i := 0; i < t.NumField(); i++ {
 		fv := r.Field(i)
 		ft := t.Field(i)
-		this.set(fv, ft, d, p, prefix)
-
+		if ft.Anonymous && fv.Kind() == reflect.Struct {
+			if err := this.fillStruct(fv, d, p, prefix); err != nil {
+				return err
+			}
+		} else if err := this.set(fv, ft, d, p, prefix); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -215,4 +285,108 @@ func (this *StructFiller) fill(s interface{}, d map[string]interface{}, p string
 // Fill takes map and populates the struct..
 func (this *StructFiller) Fill(s interface{}, d map[string]interface{}) error {
 	return this.fill(s, d, "")
-}
\ No newline at end of file
+}
+
+// decodeInto assigns a decoded value to the pointer, which can point to a Value, an interface{}, a
+// struct, which is filled from a decoded map, or any other type the value can be cast to
+func (this *StructFiller) decodeInto(v interface{}, dst interface{}) error {
+	r := reflect.ValueOf(dst)
+	if r.Kind() != reflect.Ptr || r.IsNil() {
+		return fmt.Errorf("Expected pointer, got %T", dst)
+	}
+	switch d := dst.(type) {
+	case *Value:
+		d.Set(v)
+		return nil
+	case *interface{}:
+		*d = v
+		return nil
+	}
+	if t := r.Elem().Type(); t.Kind() == reflect.Struct && t != timeType && t != bigIntType {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Cannot decode %T into %T", v, dst)
+		}
+		return this.Fill(dst, m)
+	}
+	return this.assign(r.Elem(), v, "value", "", "")
+}
+
+// asBigInt converts big integers, integers and integer strings to a new *big.Int
+func asBigInt(v interface{}) (*big.Int, bool) {
+	switch i := v.(type) {
+	case *big.Int:
+		if i == nil {
+			return nil, false
+		}
+		return new(big.Int).Set(i), true
+	case big.Int:
+		return new(big.Int).Set(&i), true
+	}
+	return scalarInteger(reflect.ValueOf(v))
+}
+
+// FieldError is an error filling a single field
+type FieldError struct {
+
+	// Field is the key the field is filled from, dotted for fields of nested structs
+	Field string
+
+	// Err is the cause
+	Err error
+}
+
+func (this *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", this.Field, this.Err)
+}
+
+// checkCast validates that strings given for numeric and bool fields can be converted
+func checkCast(fv reflect.Value, v interface{}) error {
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	if k := indirectType(fv.Type()).Kind(); IsNumericKind(k) && s != "" && !IsNumericString(s) {
+		return fmt.Errorf("Expected number, got %q", s)
+	} else if k == reflect.Bool && s != "" && !IsBoolString(s) && !IsNumericString(s) {
+		return fmt.Errorf("Expected bool, got %q", s)
+	}
+	return nil
+}
+
+// fillChecked fills all fields of the struct like Fill, but requires strings given for numeric and
+// bool fields to be parsable as such and collects the errors of all fields instead of failing on
+// the first
+func (this *StructFiller) fillChecked(r reflect.Value, d map[string]interface{}, prefix string, errs []*FieldError) []*FieldError {
+	t := r.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fv := r.Field(i)
+		ft := t.Field(i)
+		if ft.Anonymous && fv.Kind() == reflect.Struct {
+			errs = this.fillChecked(fv, d, prefix, errs)
+			continue
+		} else if !fv.CanSet() {
+			continue
+		}
+		for _, n := range this.fieldNames(ft) {
+			v, ok := d[n]
+			if !ok {
+				continue
+			}
+			if m, ok := v.(map[string]interface{}); ok && fv.Kind() == reflect.Struct && isSectionValue(fv) {
+				errs = this.fillChecked(fv, m, prefix+n+".", errs)
+				break
+			}
+			err := checkCast(fv, v)
+			if err == nil {
+				err = this.assign(fv, v, n, "", "")
+			}
+			if err != nil {
+				errs = append(errs, &FieldError{Field: prefix + n, Err: err})
+			}
+			break
+		}
+	}
+	return errs
+}
@@ -0,0 +1,199 @@
+package reflekt
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// EnvLookup returns the value of an environment variable and whether it is set
+type EnvLookup func(name string) (string, bool)
+
+// EnvMap creates an EnvLookup reading from the map instead of the process environment
+func EnvMap(env map[string]string) EnvLookup {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+// envName returns the name of the variable for the field: the tag of the filler, if set, or the
+// field name in upper snake case
+func (this *StructFiller) envName(ft reflect.StructField) string {
+	names := this.fieldNames(ft)
+	if len(names) == 0 {
+		return ""
+	} else if names[0] != ft.Name {
+		return names[0]
+	}
+	return strings.ToUpper(snakeCase(ft.Name))
+}
+
+// envCast converts the string into a value of the type, using the casters for scalars
+func envCast(s string, t reflect.Type) (reflect.Value, error) {
+	if t == valueType || t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return parseFlag(strings.TrimSpace(s), t)
+	}
+	res := reflect.New(t).Elem()
+	k := t.Kind()
+	switch {
+	case k == reflect.String:
+		res.SetString(s)
+	case k == reflect.Interface && t.NumMethod() == 0:
+		res.Set(reflect.ValueOf(s))
+	case IsScalar(res):
+		setScalar(res, strings.TrimSpace(s))
+	default:
+		return res, fmt.Errorf("Unsupported type %s", t)
+	}
+	return res, nil
+}
+
+// envValue converts the string into a value of the type, splitting slices and maps by the separator
+func envValue(s string, t reflect.Type, sep string) (interface{}, error) {
+	if reflect.PtrTo(t).Implements(scannerType) {
+		return s, nil
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		res := reflect.MakeSlice(t, 0, 0)
+		if strings.TrimSpace(s) == "" {
+			return res.Interface(), nil
+		}
+		for _, item := range strings.Split(s, sep) {
+			v, err := envCast(item, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			res = reflect.Append(res, v)
+		}
+		return res.Interface(), nil
+	case reflect.Map:
+		res := reflect.MakeMap(t)
+		if strings.TrimSpace(s) == "" {
+			return res.Interface(), nil
+		}
+		for _, item := range strings.Split(s, sep) {
+			p := strings.SplitN(item, "=", 2)
+			if len(p) != 2 {
+				return nil, fmt.Errorf("Expected K=V, got %q", item)
+			}
+			k, err := envCast(strings.TrimSpace(p[0]), t.Key())
+			if err != nil {
+				return nil, err
+			}
+			v, err := envCast(p[1], t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			res.SetMapIndex(k, v)
+		}
+		return res.Interface(), nil
+	case reflect.Ptr:
+		v, err := envCast(s, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(v)
+		return p.Interface(), nil
+	}
+	v, err := envCast(s, t)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// envMap collects the variables for all fields of the struct type into a map, which can be used
+// with Fill. Returns whether any variable was found. Fails if the names of two fields, collected
+// in names, are the same variable.
+func (this *StructFiller) envMap(t reflect.Type, prefix string, lookup EnvLookup, d map[string]interface{}, names map[string]string) (bool, error) {
+	found := false
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+			ok, err := this.envMap(ft.Type, prefix, lookup, d, names)
+			if err != nil {
+				return false, err
+			}
+			found = found || ok
+			continue
+		} else if ft.PkgPath != "" {
+			continue
+		}
+		name := this.envName(ft)
+		if name == "" {
+			continue
+		}
+		name = prefix + name
+		st := ft.Type
+		if st.Kind() == reflect.Ptr {
+			st = st.Elem()
+		}
+		if st.Kind() == reflect.Struct && st != timeType && !reflect.PtrTo(st).Implements(scannerType) &&
+			!reflect.PtrTo(st).Implements(textUnmarshalerType) {
+			sub := make(map[string]interface{})
+			ok, err := this.envMap(st, name+"_", lookup, sub, names)
+			if err != nil {
+				return false, err
+			} else if ok {
+				d[ft.Name] = sub
+				found = true
+			}
+			continue
+		} else if other, ok := names[name]; ok {
+			return false, fmt.Errorf("Fields %s and %s both use variable %s", other, ft.Name, name)
+		}
+		names[name] = ft.Name
+		s, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		sep := ft.Tag.Get("sep")
+		if sep == "" {
+			sep = ","
+		}
+		v, err := envValue(s, ft.Type, sep)
+		if err != nil {
+			return false, fmt.Errorf("Cannot use %s for %s: %s", name, ft.Name, err)
+		}
+		d[ft.Name] = v
+		found = true
+	}
+	return found, nil
+}
+
+// FillEnv fills the struct from environment variables, which are looked up with the given function
+// or os.LookupEnv if nil. Variable names are the field names in upper snake case, or the tag of the
+// filler, prefixed with the prefix and an underscore, if set. Fields of nested structs use the name
+// of the parent field as prefix. Slices and maps are read from lists separated by `,` or the `sep`
+// tag of the field, maps from `K=V` items. Fields without variables keep their values. Fails if two
+// fields use the same variable.
+func (this *StructFiller) FillEnv(s interface{}, prefix string, lookup EnvLookup) error {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	if prefix != "" {
+		prefix += "_"
+	}
+	r := reflect.ValueOf(s)
+	if r.Kind() != reflect.Ptr || r.IsNil() || r.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Expected pointer to struct, got %T", s)
+	}
+	d := make(map[string]interface{})
+	if _, err := this.envMap(r.Elem().Type(), prefix, lookup, d, make(map[string]string)); err != nil {
+		return err
+	}
+	return this.Fill(s, d)
+}
+
+// FillEnv fills the struct from environment variables, using a StructFiller which reads variable
+// names from the `env` tag. See StructFiller.FillEnv.
+func FillEnv(s interface{}, prefix string, lookup EnvLookup) error {
+	return NewStructFiller().UseTag("env").FillEnv(s, prefix, lookup)
+}
@@ -0,0 +1,103 @@
+package reflekt
+
+import (
+	"database/sql"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+type tEnvBase struct {
+	Debug bool
+}
+
+type tEnvDB struct {
+	Host     string
+	MaxConns int `env:"CONNS"`
+}
+
+type tEnvConfig struct {
+	tEnvBase
+	Name     string
+	Port     uint16
+	Ratio    float64
+	Timeout  time.Duration
+	Since    time.Time
+	Hosts    []string
+	Ports    []int `sep:";"`
+	Limits   map[string]int
+	Optional *int
+	Null     sql.NullString
+	Skip     string `env:"-"`
+	DB       tEnvDB
+	Cache    *tEnvDB
+	Other    *tEnvDB
+	internal string
+}
+
+func TestFillEnv(t *testing.T) {
+	Convey("Fill struct from environment", t, func() {
+		env := EnvMap(map[string]string{
+			"APP_DEBUG":      "1",
+			"APP_NAME":       "svc",
+			"APP_PORT":       " 8080 ",
+			"APP_RATIO":      "0.5",
+			"APP_TIMEOUT":    "1m",
+			"APP_SINCE":      "2024-01-02T03:04:05Z",
+			"APP_HOSTS":      "a,b",
+			"APP_PORTS":      "1; 2",
+			"APP_LIMITS":     "x=1,y=2",
+			"APP_OPTIONAL":   "3",
+			"APP_NULL":       "foo",
+			"APP_SKIP":       "nope",
+			"APP_DB_CONNS":   "10",
+			"APP_CACHE_HOST": "cache",
+		})
+		c := &tEnvConfig{Name: "default", DB: tEnvDB{Host: "localhost"}, internal: "keep"}
+		So(FillEnv(c, "APP", env), ShouldBeNil)
+		three := 3
+		So(c, ShouldResemble, &tEnvConfig{
+			tEnvBase: tEnvBase{Debug: true},
+			Name:     "svc",
+			Port:     8080,
+			Ratio:    0.5,
+			Timeout:  time.Minute,
+			Since:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Hosts:    []string{"a", "b"},
+			Ports:    []int{1, 2},
+			Limits:   map[string]int{"x": 1, "y": 2},
+			Optional: &three,
+			Null:     sql.NullString{String: "foo", Valid: true},
+			DB:       tEnvDB{Host: "localhost", MaxConns: 10},
+			Cache:    &tEnvDB{Host: "cache"},
+			internal: "keep",
+		})
+	})
+	Convey("Fill without prefix and keep values", t, func() {
+		c := &tEnvDB{Host: "localhost"}
+		So(NewStructFiller().FillEnv(c, "", EnvMap(map[string]string{"MAX_CONNS": "5"})), ShouldBeNil)
+		So(c, ShouldResemble, &tEnvDB{Host: "localhost", MaxConns: 5})
+	})
+	Convey("Fill fields with acronyms", t, func() {
+		c := &struct {
+			User   string
+			UserID int
+		}{}
+		So(FillEnv(c, "APP", EnvMap(map[string]string{"APP_USER": "bob", "APP_USER_ID": "5"})), ShouldBeNil)
+		So(c.User, ShouldEqual, "bob")
+		So(c.UserID, ShouldEqual, 5)
+	})
+	Convey("Fail on invalid values", t, func() {
+		So(FillEnv(&struct {
+			User   string `env:"USER_ID"`
+			UserID int
+		}{}, "", nil), ShouldNotBeNil)
+		So(FillEnv(&struct {
+			DBHost string
+			DB     struct{ Host string }
+		}{}, "", nil), ShouldNotBeNil)
+		So(FillEnv(&tEnvConfig{}, "", EnvMap(map[string]string{"LIMITS": "x"})), ShouldNotBeNil)
+		So(FillEnv(&tEnvConfig{}, "", EnvMap(map[string]string{"TIMEOUT": "soon"})), ShouldNotBeNil)
+		So(FillEnv(tEnvConfig{}, "", nil), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,183 @@
+package reflekt
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// StructFlags are flags registered for the fields of a struct, which fill the struct after parsing
+type StructFlags struct {
+	filler *StructFiller
+	ptr    interface{}
+	fs     *flag.FlagSet
+	names  []string
+	paths  map[string][]string
+	values map[string]reflect.Value
+}
+
+// isFlagType checks whether values of the type can be parsed by NewFlag
+func isFlagType(t reflect.Type) bool {
+	k := t.Kind()
+	return t == valueType || t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType) ||
+		k == reflect.String || k == reflect.Bool || IsNumericKind(k) ||
+		(k == reflect.Interface && t.NumMethod() == 0)
+}
+
+// flagName returns the name of the flag for the field: the tag of the filler, if set, or the
+// field name in snake case, with dashes instead of underscores
+func (this *StructFiller) flagName(ft reflect.StructField) string {
+	names := this.fieldNames(ft)
+	if len(names) == 0 {
+		return ""
+	} else if names[0] != ft.Name {
+		return names[0]
+	}
+	return strings.Replace(snakeCase(ft.Name), "_", "-", -1)
+}
+
+// Flags registers one flag per exported field of the struct on the flag set. Fields of nested and
+// embedded structs are registered with the dotted names of the parent fields as prefix, or no
+// prefix if embedded. Pointers are dereferenced, nil pointers to structs are allocated. The current
+// field values are used as defaults and the `help` tag as usage. Fields of types not supported by
+// NewFlag, NewSliceFlag or NewMapFlag are ignored. Fails if two fields use the same flag name or a
+// flag is already defined on the flag set. Call Fill on the result after the flag set has been
+// parsed.
+func (this *StructFiller) Flags(fs *flag.FlagSet, ptr interface{}) (*StructFlags, error) {
+	r := reflect.ValueOf(ptr)
+	if r.Kind() != reflect.Ptr || r.IsNil() || r.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Expected pointer to struct, got %T", ptr)
+	}
+	res := &StructFlags{
+		filler: this,
+		ptr:    ptr,
+		fs:     fs,
+		names:  []string{},
+		paths:  make(map[string][]string),
+		values: make(map[string]reflect.Value),
+	}
+	if err := res.register(r.Elem(), "", []string{}, make(map[string]string), make(map[reflect.Type]bool)); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// register registers the flags for the fields of the struct. Nil pointers to structs are
+// allocated. Pointers to types already on the path are ignored, to not recurse endlessly.
+func (this *StructFlags) register(r reflect.Value, prefix string, path []string, fields map[string]string, types map[reflect.Type]bool) error {
+	t := r.Type()
+	types[t] = true
+	defer delete(types, t)
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		fv := r.Field(i)
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+			if err := this.register(fv, prefix, path, fields, types); err != nil {
+				return err
+			}
+			continue
+		} else if ft.PkgPath != "" {
+			continue
+		}
+		name := this.filler.flagName(ft)
+		if name == "" {
+			continue
+		}
+		name = prefix + name
+		fp := append(append([]string{}, path...), ft.Name)
+		field := strings.Join(fp, ".")
+		et := ft.Type
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		k := et.Kind()
+
+		if !isFlagType(et) && k == reflect.Struct {
+			if ft.Type.Kind() == reflect.Ptr {
+				if types[et] {
+					continue
+				} else if fv.IsNil() {
+					fv.Set(reflect.New(et))
+				}
+				fv = fv.Elem()
+			}
+			if err := this.register(fv, name+".", fp, fields, types); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var v flag.Value
+		store := reflect.New(et)
+		if ft.Type.Kind() != reflect.Ptr {
+			store.Elem().Set(fv)
+		} else if !fv.IsNil() {
+			store.Elem().Set(fv.Elem())
+		}
+		switch {
+		case isFlagType(et):
+			v = NewFlag(store.Interface())
+		case k == reflect.Slice && isFlagType(et.Elem()):
+			v = NewSliceFlag(store.Interface())
+		case k == reflect.Map && isFlagType(et.Key()) && isFlagType(et.Elem()):
+			v = NewMapFlag(store.Interface())
+		default:
+			continue
+		}
+		if other, ok := fields[name]; ok {
+			return fmt.Errorf("Fields %s and %s both use flag %s", other, field, name)
+		} else if this.fs.Lookup(name) != nil {
+			return fmt.Errorf("Flag %s of field %s is already defined", name, field)
+		}
+		fields[name] = field
+		this.fs.Var(v, name, ft.Tag.Get("help"))
+		this.names = append(this.names, name)
+		this.paths[name] = fp
+		if ft.Type.Kind() == reflect.Ptr {
+			this.values[name] = store
+		} else {
+			this.values[name] = store.Elem()
+		}
+	}
+	return nil
+}
+
+// Names returns the names of all registered flags, in order of the struct fields
+func (this *StructFlags) Names() []string {
+	return append([]string{}, this.names...)
+}
+
+// Fill fills the struct with the values of all registered flags, which are either parsed or
+// still the defaults
+func (this *StructFlags) Fill() error {
+	d := make(map[string]interface{})
+	for _, name := range this.names {
+		m := d
+		path := this.paths[name]
+		for _, p := range path[:len(path)-1] {
+			sub, ok := m[p].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				m[p] = sub
+			}
+			m = sub
+		}
+		m[path[len(path)-1]] = this.values[name].Interface()
+	}
+	return this.filler.Fill(this.ptr, d)
+}
+
+// Parse parses the arguments with the flag set and fills the struct
+func (this *StructFlags) Parse(args []string) error {
+	if err := this.fs.Parse(args); err != nil {
+		return err
+	}
+	return this.Fill()
+}
+
+// BindFlags registers flags for the fields of the struct on the flag set, using a StructFiller
+// which reads flag names from the `flag` tag. See StructFiller.Flags.
+func BindFlags(fs *flag.FlagSet, ptr interface{}) (*StructFlags, error) {
+	return NewStructFiller().UseTag("flag").Flags(fs, ptr)
+}
@@ -0,0 +1,116 @@
+package reflekt
+
+import (
+	"flag"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+type tFlagBase struct {
+	Verbose bool `help:"Verbose output"`
+}
+
+type tFlagDB struct {
+	Host     string `help:"Database host"`
+	MaxConns int    `flag:"max"`
+	Opts     map[string]string
+	internal string
+}
+
+type tFlagConfig struct {
+	tFlagBase
+	Name    string        `help:"Service name"`
+	Timeout time.Duration `help:"Request timeout"`
+	Tags    []string
+	Ratio   float64
+	Skip    string `flag:"-"`
+	Func    func()
+	DB      tFlagDB
+}
+
+type tFlagPort struct {
+	Port int
+}
+
+type tFlagPorts struct {
+	tFlagPort
+	Port int
+}
+
+type tFlagTags struct {
+	A string `flag:"x"`
+	B string `flag:"x"`
+}
+
+type tFlagPtrs struct {
+	Name    *string
+	Timeout *int
+	DB      *tFlagDB
+	Parent  *tFlagPtrs
+}
+
+func TestBindFlags(t *testing.T) {
+	Convey("Register flags from struct", t, func() {
+		c := &tFlagConfig{Name: "svc", Timeout: time.Second, Tags: []string{"a"}, DB: tFlagDB{Host: "localhost", MaxConns: 5, internal: "keep"}}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		f, err := BindFlags(fs, c)
+		So(err, ShouldBeNil)
+		So(f.Names(), ShouldResemble, []string{"verbose", "name", "timeout", "tags", "ratio", "db.host", "db.max", "db.opts"})
+		So(fs.Lookup("name").DefValue, ShouldEqual, "svc")
+		So(fs.Lookup("name").Usage, ShouldEqual, "Service name")
+		So(fs.Lookup("timeout").DefValue, ShouldEqual, "1s")
+		So(fs.Lookup("db.max").DefValue, ShouldEqual, "5")
+
+		Convey("Fill parsed values and keep defaults", func() {
+			So(f.Parse([]string{"-verbose", "-timeout", "1m", "-tags", "x,y", "-ratio", "1.5k", "-db.max", "2k", "-db.opts", "a=b"}), ShouldBeNil)
+			So(c, ShouldResemble, &tFlagConfig{
+				tFlagBase: tFlagBase{Verbose: true},
+				Name:      "svc",
+				Timeout:   time.Minute,
+				Tags:      []string{"x", "y"},
+				Ratio:     1500,
+				DB:        tFlagDB{Host: "localhost", MaxConns: 2000, Opts: map[string]string{"a": "b"}, internal: "keep"},
+			})
+		})
+
+		Convey("Fail on invalid values", func() {
+			So(f.Parse([]string{"-db.max", "lots"}), ShouldNotBeNil)
+		})
+	})
+	Convey("Register flags for pointer fields", t, func() {
+		name := "svc"
+		c := &tFlagPtrs{Name: &name}
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		f, err := BindFlags(fs, c)
+		So(err, ShouldBeNil)
+		So(f.Names(), ShouldResemble, []string{"name", "timeout", "db.host", "db.max", "db.opts"})
+		So(fs.Lookup("name").DefValue, ShouldEqual, "svc")
+		So(c.DB, ShouldNotBeNil)
+
+		c.DB.internal = "keep"
+		So(f.Parse([]string{"-timeout", "5", "-db.host", "localhost"}), ShouldBeNil)
+		So(*c.Name, ShouldEqual, "svc")
+		So(*c.Timeout, ShouldEqual, 5)
+		So(c.DB, ShouldResemble, &tFlagDB{Host: "localhost", internal: "keep"})
+		So(c.Parent, ShouldBeNil)
+	})
+	Convey("Fail on duplicate flag names", t, func() {
+		_, err := BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), &tFlagPorts{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Fields Port and Port both use flag port")
+		_, err = BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), &tFlagTags{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Fields A and B both use flag x")
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Int("port", 0, "")
+		_, err = BindFlags(fs, &tFlagPort{})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Reject non structs", t, func() {
+		_, err := BindFlags(flag.NewFlagSet("test", flag.ContinueOnError), map[string]int{})
+		So(err, ShouldNotBeNil)
+	})
+}
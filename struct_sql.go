@@ -0,0 +1,113 @@
+package reflekt
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// keys returns all keys the struct type can be filled from
+func (this *StructFiller) keys(t reflect.Type, res map[string]bool) map[string]bool {
+	if res == nil {
+		res = make(map[string]bool)
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return res
+	}
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+			this.keys(ft.Type, res)
+		} else if ft.PkgPath == "" {
+			for _, n := range this.fieldNames(ft) {
+				res[n] = true
+			}
+		}
+	}
+	return res
+}
+
+// ScanRows fills dest from the query results. dest must be a pointer to a struct, which is filled
+// from the first row, or a pointer to a slice of structs or struct pointers, to which all rows are
+// appended. Columns are matched to fields as in Fill. Returns the names of all columns which could
+// not be matched to any field.
+func (this *StructFiller) ScanRows(rows *sql.Rows, dest interface{}) ([]string, error) {
+	r := reflect.ValueOf(dest)
+	if r.Kind() != reflect.Ptr || r.IsNil() {
+		return nil, fmt.Errorf("Expected pointer to struct or slice, got %s", r.Kind())
+	}
+	r = r.Elem()
+	single := r.Kind() == reflect.Struct
+	var et reflect.Type
+	if single {
+		et = r.Type()
+	} else if r.Kind() == reflect.Slice {
+		et = r.Type().Elem()
+	} else {
+		return nil, fmt.Errorf("Expected pointer to struct or slice, got pointer to %s", r.Kind())
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	keys := this.keys(et, nil)
+	unmapped := []string{}
+	for _, c := range columns {
+		if !keys[c] {
+			unmapped = append(unmapped, c)
+		}
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	found := false
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return unmapped, err
+		}
+		d := make(map[string]interface{}, len(columns))
+		for i, c := range columns {
+			d[c] = values[i]
+		}
+		if single {
+			found = true
+			if err := this.Fill(r.Addr().Interface(), d); err != nil {
+				return unmapped, err
+			}
+			break
+		}
+		if et.Kind() == reflect.Ptr {
+			e := reflect.New(et.Elem())
+			if err := this.Fill(e.Interface(), d); err != nil {
+				return unmapped, err
+			}
+			r.Set(reflect.Append(r, e))
+		} else {
+			e := reflect.New(et)
+			if err := this.Fill(e.Interface(), d); err != nil {
+				return unmapped, err
+			}
+			r.Set(reflect.Append(r, e.Elem()))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return unmapped, err
+	} else if single && !found {
+		return unmapped, sql.ErrNoRows
+	}
+	return unmapped, nil
+}
+
+// ScanRows fills a struct or a slice of structs from the query results, using a StructFiller
+// which matches columns with the `db` tag, field names or their snake case. See
+// StructFiller.ScanRows.
+func ScanRows(rows *sql.Rows, dest interface{}) ([]string, error) {
+	return NewStructFiller().UseTag("db").ScanRows(rows, dest)
+}
@@ -0,0 +1,116 @@
+package reflekt
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+type tRowBase struct {
+	ID int64 `db:"user_id"`
+}
+
+type tRow struct {
+	tRowBase
+	FirstName string
+	Age       uint8
+	Score     *float64
+	Active    bool
+	Created   time.Time
+	Nick      sql.NullString
+	Ignored   string `db:"-"`
+	hidden    string
+}
+
+func TestScanRows(t *testing.T) {
+	db := openFakeDB()
+	defer db.Close()
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	testFakeDriver.result("SELECT users", []string{"user_id", "first_name", "age", "score", "active", "created", "nick", "ignored", "extra"},
+		[]driver.Value{int64(1), []byte("Alice"), "42", 1.5, int64(1), created, "ally", "x", "y"},
+		[]driver.Value{"2", "Bob", int64(23), nil, "false", created, nil, "x", "y"},
+	)
+	testFakeDriver.result("SELECT nobody", []string{"user_id"})
+
+	Convey("Scan rows into slice of structs", t, func() {
+		rows, err := db.Query("SELECT users")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		res := []tRow{}
+		unmapped, err := ScanRows(rows, &res)
+		So(err, ShouldBeNil)
+		So(unmapped, ShouldResemble, []string{"ignored", "extra"})
+		So(len(res), ShouldEqual, 2)
+		score := 1.5
+		So(res[0], ShouldResemble, tRow{
+			tRowBase:  tRowBase{ID: 1},
+			FirstName: "Alice",
+			Age:       42,
+			Score:     &score,
+			Active:    true,
+			Created:   created,
+			Nick:      sql.NullString{String: "ally", Valid: true},
+		})
+		So(res[1], ShouldResemble, tRow{
+			tRowBase:  tRowBase{ID: 2},
+			FirstName: "Bob",
+			Age:       23,
+			Created:   created,
+		})
+	})
+
+	Convey("Scan rows into slice of struct pointers", t, func() {
+		rows, err := db.Query("SELECT users")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		res := []*tRow{}
+		_, err = ScanRows(rows, &res)
+		So(err, ShouldBeNil)
+		So(len(res), ShouldEqual, 2)
+		So(res[1].FirstName, ShouldEqual, "Bob")
+	})
+
+	Convey("Scan first row into struct", t, func() {
+		rows, err := db.Query("SELECT users")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		res := tRow{}
+		_, err = ScanRows(rows, &res)
+		So(err, ShouldBeNil)
+		So(res.FirstName, ShouldEqual, "Alice")
+	})
+
+	Convey("Scan with custom filler and tag", t, func() {
+		rows, err := db.Query("SELECT users")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		res := []tRow{}
+		unmapped, err := NewStructFiller().ScanRows(rows, &res)
+		So(err, ShouldBeNil)
+		So(unmapped, ShouldResemble, []string{"user_id", "extra"})
+		So(res[0].ID, ShouldEqual, 0)
+		So(res[0].Ignored, ShouldEqual, "x")
+	})
+
+	Convey("Fail on empty result for single struct", t, func() {
+		rows, err := db.Query("SELECT nobody")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		res := tRow{}
+		_, err = ScanRows(rows, &res)
+		So(err, ShouldEqual, sql.ErrNoRows)
+	})
+
+	Convey("Fail on invalid destination", t, func() {
+		rows, err := db.Query("SELECT users")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		_, err = ScanRows(rows, tRow{})
+		So(err, ShouldNotBeNil)
+		m := map[string]interface{}{}
+		_, err = ScanRows(rows, &m)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type t1 struct {
@@ -164,3 +165,78 @@ func TestFillStruct(t *testing.T) {
 		}
 	})
 }
+
+type tFill struct {
+	t3
+	Name     string `fill:"full_name"`
+	Age      uint
+	Ratio    float32
+	Enabled  bool
+	MaxCount *int
+	Skip     string `fill:"-"`
+	Sub      t2
+}
+
+func TestStructFiller_Fill(t *testing.T) {
+	Convey("Fill struct with lenient casting", t, func() {
+		s := &tFill{}
+		err := NewStructFiller().UseTag("fill").Fill(s, map[string]interface{}{
+			"Foo":       "embedded",
+			"full_name": []byte("foo"),
+			"age":       "42",
+			"Ratio":     1,
+			"enabled":   "true",
+			"max_count": 3.0,
+			"Skip":      "x",
+			"sub":       map[string]interface{}{"D": "4"},
+		})
+		So(err, ShouldBeNil)
+		max := 3
+		So(s, ShouldResemble, &tFill{
+			t3:       t3{Foo: "embedded"},
+			Name:     "foo",
+			Age:      42,
+			Ratio:    1,
+			Enabled:  true,
+			MaxCount: &max,
+			Sub:      t2{D: 4},
+		})
+	})
+	Convey("Fill slices, maps and text values", t, func() {
+		s := &struct {
+			Ints  []int
+			Subs  []*t2
+			Map   map[string]float64
+			Since time.Time
+		}{}
+		err := NewStructFiller().Fill(s, map[string]interface{}{
+			"Ints":  []interface{}{"1", 2.0},
+			"Subs":  []interface{}{map[string]interface{}{"D": "4"}},
+			"Map":   map[string]interface{}{"a": "1.5"},
+			"Since": "2024-01-02T03:04:05Z",
+		})
+		So(err, ShouldBeNil)
+		So(s.Ints, ShouldResemble, []int{1, 2})
+		So(s.Subs, ShouldResemble, []*t2{{D: 4}})
+		So(s.Map, ShouldResemble, map[string]float64{"a": 1.5})
+		So(s.Since, ShouldResemble, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	})
+	Convey("Fill by snake case names with acronyms", t, func() {
+		s := &struct {
+			Port     int
+			HTTPPort int
+			UserID   int
+		}{}
+		err := NewStructFiller().Fill(s, map[string]interface{}{"port": 80, "http_port": 8080, "user_id": 5})
+		So(err, ShouldBeNil)
+		So(s.Port, ShouldEqual, 80)
+		So(s.HTTPPort, ShouldEqual, 8080)
+		So(s.UserID, ShouldEqual, 5)
+	})
+	Convey("Fail on invalid values", t, func() {
+		err := NewStructFiller().Fill(&tFill{}, map[string]interface{}{"Sub": 1})
+		So(err, ShouldNotBeNil)
+		err = NewStructFiller().Fill(&struct{ Since time.Time }{}, map[string]interface{}{"Since": "soon"})
+		So(err, ShouldNotBeNil)
+	})
+}
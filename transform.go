@@ -0,0 +1,223 @@
+package reflekt
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// TransformRule describes which nodes of a value tree are to be changed and how. All set
+// matchers must match for the rule to be applied.
+type TransformRule struct {
+
+	// Kind matches the kind of the value, if not reflect.Invalid
+	Kind reflect.Kind
+
+	// Type matches the exact type of the value, if not nil
+	Type reflect.Type
+
+	// Path matches the dotted path of the value, if not empty. See Walk for path segments.
+	// A `*` segment matches any one segment, a `**` segment any amount of segments
+	Path string
+
+	// Tag matches the struct field containing the value, if not empty. Either only the tag
+	// key (eg `transform`) or key and one of the comma separated values (eg `transform:trim`)
+	Tag string
+
+	// Keys applies the rule to the keys of maps instead of the values. Kind and Type are
+	// matched against the key, Path against the path of the map entry.
+	Keys bool
+
+	// Func returns the transformed value
+	Func func(v interface{}) (interface{}, error)
+}
+
+func (this *TransformRule) match(v reflect.Value, path []string, field *reflect.StructField) bool {
+	if this.Kind != reflect.Invalid && v.Kind() != this.Kind {
+		return false
+	} else if this.Type != nil && v.Type() != this.Type {
+		return false
+	} else if this.Path != "" && !matchPath(this.Path, path) {
+		return false
+	} else if this.Tag != "" && !matchTag(this.Tag, field) {
+		return false
+	}
+	return true
+}
+
+func matchTag(tag string, field *reflect.StructField) bool {
+	if field == nil {
+		return false
+	}
+	key, val := tag, ""
+	if i := strings.Index(tag, ":"); i > -1 {
+		key, val = tag[:i], tag[i+1:]
+	}
+	found, ok := field.Tag.Lookup(key)
+	if !ok {
+		return false
+	} else if val == "" {
+		return true
+	}
+	for _, v := range strings.Split(found, ",") {
+		if strings.TrimSpace(v) == val {
+			return true
+		}
+	}
+	return false
+}
+
+// Transformer applies an ordered list of rules to all nodes of arbitrary nested values
+type Transformer struct {
+	rules []*TransformRule
+}
+
+// NewTransformer creates a transformer with the given rules
+func NewTransformer(rules ...*TransformRule) *Transformer {
+	return &Transformer{
+		rules: rules,
+	}
+}
+
+// Rule adds another rule, which is applied after all already added rules
+func (this *Transformer) Rule(rule *TransformRule) *Transformer {
+	this.rules = append(this.rules, rule)
+	return this
+}
+
+// Apply transforms the value in place. All changed values must be replaceable, hence v must
+// be a pointer, map or slice.
+func (this *Transformer) Apply(v interface{}) error {
+	return Walk(v, this.visit)
+}
+
+// Transform returns a transformed copy and leaves v untouched
+func (this *Transformer) Transform(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	r := reflect.New(reflect.TypeOf(v))
+	r.Elem().Set(deepCopy(reflect.ValueOf(v)))
+	if err := this.Apply(r.Interface()); err != nil {
+		return nil, err
+	}
+	return r.Elem().Interface(), nil
+}
+
+func (this *Transformer) visit(n *WalkNode) error {
+	if n.Value.Kind() == reflect.Interface || !n.Value.CanInterface() {
+		return nil
+	}
+	if n.Value.Kind() == reflect.Map && !n.Value.IsNil() {
+		if err := this.transformKeys(n); err != nil {
+			return err
+		}
+	}
+	v := n.Value
+	changed := false
+	for _, rule := range this.rules {
+		if rule.Keys || !v.IsValid() || !rule.match(v, n.Path, n.Field) {
+			continue
+		}
+		res, err := rule.Func(v.Interface())
+		if err != nil {
+			return fmt.Errorf("Failed to transform %s: %s", n.PathString(), err)
+		}
+		v = reflect.ValueOf(res)
+		changed = true
+	}
+	if changed {
+		return n.Replace(v)
+	}
+	return nil
+}
+
+func (this *Transformer) transformKeys(n *WalkNode) error {
+	r := n.Value
+	kt := r.Type().Key()
+	for _, k := range r.MapKeys() {
+		path := appendPath(n.Path, fmt.Sprintf("%v", k.Interface()))
+		nk := k
+		if nk.Kind() == reflect.Interface && !nk.IsNil() {
+			nk = nk.Elem()
+		}
+		for _, rule := range this.rules {
+			if !rule.Keys || !rule.match(nk, path, n.Field) {
+				continue
+			}
+			res, err := rule.Func(nk.Interface())
+			if err != nil {
+				return fmt.Errorf("Failed to transform key %s: %s", strings.Join(path, "."), err)
+			}
+			nk = reflect.ValueOf(res)
+			if !nk.IsValid() {
+				return fmt.Errorf("Failed to transform key %s: nil key", strings.Join(path, "."))
+			}
+		}
+		if nk.Interface() != k.Interface() {
+			if nk, err := assignable(nk, kt); err != nil {
+				return fmt.Errorf("Failed to transform key %s: %s", strings.Join(path, "."), err)
+			} else if r.MapIndex(nk).IsValid() {
+				return fmt.Errorf("Failed to transform key %s: %v already exists", strings.Join(path, "."), nk.Interface())
+			} else {
+				v := r.MapIndex(k)
+				r.SetMapIndex(k, reflect.Value{})
+				r.SetMapIndex(nk, v)
+			}
+		}
+	}
+	return nil
+}
+
+// TrimStrings returns a rule removing leading and trailing white space from all strings
+func TrimStrings() *TransformRule {
+	return &TransformRule{
+		Kind: reflect.String,
+		Func: func(v interface{}) (interface{}, error) {
+			r := reflect.ValueOf(v)
+			return reflect.ValueOf(strings.TrimSpace(r.String())).Convert(r.Type()).Interface(), nil
+		},
+	}
+}
+
+// LowerKeys returns a rule lower casing all string map keys. Keys which are equal when lower cased
+// fail the transformation.
+func LowerKeys() *TransformRule {
+	return &TransformRule{
+		Kind: reflect.String,
+		Keys: true,
+		Func: func(v interface{}) (interface{}, error) {
+			r := reflect.ValueOf(v)
+			return reflect.ValueOf(strings.ToLower(r.String())).Convert(r.Type()).Interface(), nil
+		},
+	}
+}
+
+// IntegralFloats returns a rule converting all float64 without fraction into int64
+func IntegralFloats() *TransformRule {
+	return &TransformRule{
+		Type: reflect.TypeOf(float64(0)),
+		Func: func(v interface{}) (interface{}, error) {
+			f := v.(float64)
+			if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+				return int64(f), nil
+			}
+			return f, nil
+		},
+	}
+}
+
+// EmptySlices returns a rule replacing nil slices with empty slices of the same type
+func EmptySlices() *TransformRule {
+	return &TransformRule{
+		Kind: reflect.Slice,
+		Func: func(v interface{}) (interface{}, error) {
+			r := reflect.ValueOf(v)
+			if r.IsNil() {
+				return reflect.MakeSlice(r.Type(), 0, 0).Interface(), nil
+			}
+			return v, nil
+		},
+	}
+}
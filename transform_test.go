@@ -0,0 +1,138 @@
+package reflekt
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tTransformString string
+
+type tTransform struct {
+	Name  string `transform:"upper,trim"`
+	Other string
+	Tags  []string
+	Attrs map[string]interface{}
+}
+
+var testsTransform = []struct {
+	rules []*TransformRule
+	from  interface{}
+	to    interface{}
+}{
+	{
+		rules: []*TransformRule{TrimStrings()},
+		from:  map[string]interface{}{"foo": " bar ", "baz": []interface{}{" qux"}},
+		to:    map[string]interface{}{"foo": "bar", "baz": []interface{}{"qux"}},
+	},
+	{
+		rules: []*TransformRule{LowerKeys()},
+		from:  map[string]interface{}{"Foo": map[interface{}]interface{}{"BAR": 1, 2: 3}},
+		to:    map[string]interface{}{"foo": map[interface{}]interface{}{"bar": 1, 2: 3}},
+	},
+	{
+		rules: []*TransformRule{TrimStrings(), LowerKeys()},
+		from:  map[tTransformString]tTransformString{"FOO": " bar "},
+		to:    map[tTransformString]tTransformString{"foo": "bar"},
+	},
+	{
+		rules: []*TransformRule{IntegralFloats()},
+		from:  []interface{}{1.0, 1.5, float32(2.0), "3", math.Pow(2, 63), -math.Pow(2, 63)},
+		to:    []interface{}{int64(1), 1.5, float32(2.0), "3", math.Pow(2, 63), int64(math.MinInt64)},
+	},
+	{
+		rules: []*TransformRule{EmptySlices()},
+		from:  tTransform{Name: "foo"},
+		to:    tTransform{Name: "foo", Tags: []string{}},
+	},
+	{
+		rules: []*TransformRule{
+			{
+				Tag: "transform:upper",
+				Func: func(v interface{}) (interface{}, error) {
+					return strings.ToUpper(AsString(v)), nil
+				},
+			},
+		},
+		from: tTransform{Name: "foo", Other: "bar"},
+		to:   tTransform{Name: "FOO", Other: "bar"},
+	},
+	{
+		rules: []*TransformRule{
+			{
+				Path: "Attrs.*",
+				Func: func(v interface{}) (interface{}, error) {
+					return AsInt(v), nil
+				},
+			},
+		},
+		from: tTransform{Name: "1", Attrs: map[string]interface{}{"foo": "2", "bar": 3.5}},
+		to:   tTransform{Name: "1", Attrs: map[string]interface{}{"foo": 2, "bar": 3}},
+	},
+	{
+		rules: []*TransformRule{
+			TrimStrings(),
+			{
+				Kind: reflect.String,
+				Func: func(v interface{}) (interface{}, error) {
+					return v.(string) + "!", nil
+				},
+			},
+		},
+		from: []string{" foo "},
+		to:   []string{"foo!"},
+	},
+	{
+		rules: []*TransformRule{TrimStrings()},
+		from:  map[string]tTransform{"x": {Name: " a ", Tags: []string{" b"}}},
+		to:    map[string]tTransform{"x": {Name: "a", Tags: []string{"b"}}},
+	},
+	{
+		rules: []*TransformRule{TrimStrings()},
+		from:  map[string]interface{}{"x": [2]string{" a", "b "}},
+		to:    map[string]interface{}{"x": [2]string{"a", "b"}},
+	},
+}
+
+func TestTransformer_Transform(t *testing.T) {
+	Convey("Transform copies of values", t, func() {
+		for i, test := range testsTransform {
+			Convey(fmt.Sprintf("%d) From %s", i, typeName(test.from)), func() {
+				before := fmt.Sprintf("%#v", test.from)
+				to, err := NewTransformer(test.rules...).Transform(test.from)
+				So(err, ShouldBeNil)
+				So(to, ShouldResemble, test.to)
+				So(fmt.Sprintf("%#v", test.from), ShouldEqual, before)
+			})
+		}
+	})
+}
+
+func TestTransformer_Apply(t *testing.T) {
+	Convey("Transform values in place", t, func() {
+		v := &tTransform{Name: " foo ", Attrs: map[string]interface{}{"X": " y "}}
+		err := NewTransformer().Rule(TrimStrings()).Rule(LowerKeys()).Apply(v)
+		So(err, ShouldBeNil)
+		So(v, ShouldResemble, &tTransform{Name: "foo", Attrs: map[string]interface{}{"x": "y"}})
+	})
+	Convey("Fail on values which cannot be replaced", t, func() {
+		err := NewTransformer(TrimStrings()).Apply(tTransform{Name: " foo "})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Fail on colliding keys", t, func() {
+		err := NewTransformer(LowerKeys()).Apply(map[string]interface{}{"A": 1, "a": 2})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Fail on rule errors", t, func() {
+		err := NewTransformer(&TransformRule{
+			Func: func(v interface{}) (interface{}, error) {
+				return nil, fmt.Errorf("Nope")
+			},
+		}).Apply([]int{1})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Failed to transform : Nope")
+	})
+}
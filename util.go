@@ -1,25 +1,120 @@
 package reflekt
 
 import (
-	"regexp"
+	"reflect"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
-var snakeCaseRegex = regexp.MustCompile(`(^[\p{Ll}\p{N}]+)?(\p{Lu}[\p{Ll}\p{N}]+)`)
-
+// snakeCase converts a camel case name into snake case. Acronyms are kept as one word, so that
+// `HTTPPort` becomes `http_port` and `UserID` becomes `user_id`.
 func snakeCase(str string) string {
-	chunks := snakeCaseRegex.FindAllStringSubmatch(str, -1)
-	if chunks == nil {
-		return strings.ToLower(str)
-	}
-	sep := "_"
-	found := []string{}
-	for _, chunk := range chunks {
-		for i := 1; i < len(chunk); i++ {
-			if len(chunk[i]) > 0 {
-				found = append(found, strings.ToLower(chunk[i]))
+	runes := []rune(str)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// appendPath returns a new path with the segment appended, without modifying the given path
+func appendPath(path []string, seg string) []string {
+	res := make([]string, len(path)+1)
+	copy(res, path)
+	res[len(path)] = seg
+	return res
+}
+
+// matchPath checks whether the dotted path pattern matches the path. A `*` segment in the pattern
+// matches exactly one path segment, a `**` segment matches any amount of segments.
+func matchPath(pattern string, path []string) bool {
+	if pattern == "" {
+		return len(path) == 0
+	}
+	return matchPathSegments(strings.Split(pattern, "."), path)
+}
+
+func matchPathSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchPathSegments(pattern[1:], path[i:]) {
+					return true
+				}
 			}
+			return false
+		} else if len(path) == 0 || (pattern[0] != "*" && pattern[0] != path[0]) {
+			return false
+		}
+		pattern = pattern[1:]
+		path = path[1:]
+	}
+	return len(path) == 0
+}
+
+// reflectValue returns reflect.ValueOf(v) unless v already is a reflect.Value
+func reflectValue(v interface{}) reflect.Value {
+	if r, ok := v.(reflect.Value); ok {
+		return r
+	}
+	return reflect.ValueOf(v)
+}
+
+// indirect dereferences pointers and interfaces until a concrete value or nil is reached
+func indirect(r reflect.Value) reflect.Value {
+	for r.Kind() == reflect.Ptr || r.Kind() == reflect.Interface {
+		if r.IsNil() {
+			return reflect.Value{}
+		}
+		r = r.Elem()
+	}
+	return r
+}
+
+// indexedLists converts maps with the keys 0 to n-1 into slices, recursively
+func indexedLists(m map[string]interface{}) interface{} {
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			m[k] = indexedLists(sub)
+		}
+	}
+	list := make([]interface{}, len(m))
+	for k, v := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != k {
+			return m
 		}
+		list[i] = v
+	}
+	if len(list) == 0 {
+		return m
+	}
+	return list
+}
+
+// indexedMaps converts the nested maps of the map with the keys 0 to n-1 into slices, like
+// indexedLists, but keeps the map itself
+func indexedMaps(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			m[k] = indexedLists(sub)
+		}
+	}
+	return m
+}
+
+// indirectType returns the type pointers point to
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
-	return strings.Join(found, sep)
+	return t
 }
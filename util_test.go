@@ -1,8 +1,9 @@
 package reflekt
+
 import (
-	"testing"
 	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"testing"
 )
 
 var testSnakeCases = []struct {
@@ -37,6 +38,22 @@ var testSnakeCases = []struct {
 		from: "fooBarBaz",
 		to:   "foo_bar_baz",
 	},
+	{
+		from: "HTTPPort",
+		to:   "http_port",
+	},
+	{
+		from: "UserID",
+		to:   "user_id",
+	},
+	{
+		from: "APIKey2",
+		to:   "api_key2",
+	},
+	{
+		from: "Field2Name",
+		to:   "field2_name",
+	},
 }
 
 func TestSnakeCase(t *testing.T) {
@@ -48,4 +65,66 @@ func TestSnakeCase(t *testing.T) {
 			})
 		}
 	})
-}
\ No newline at end of file
+}
+
+var testMatchPaths = []struct {
+	pattern string
+	path    []string
+	match   bool
+}{
+	{
+		pattern: "",
+		path:    []string{},
+		match:   true,
+	},
+	{
+		pattern: "foo",
+		path:    []string{"foo"},
+		match:   true,
+	},
+	{
+		pattern: "foo",
+		path:    []string{"foo", "bar"},
+		match:   false,
+	},
+	{
+		pattern: "foo.*",
+		path:    []string{"foo", "bar"},
+		match:   true,
+	},
+	{
+		pattern: "*.bar",
+		path:    []string{"foo", "baz"},
+		match:   false,
+	},
+	{
+		pattern: "**",
+		path:    []string{},
+		match:   true,
+	},
+	{
+		pattern: "**.bar",
+		path:    []string{"foo", "0", "bar"},
+		match:   true,
+	},
+	{
+		pattern: "foo.**.baz",
+		path:    []string{"foo", "baz"},
+		match:   true,
+	},
+	{
+		pattern: "foo.**.baz",
+		path:    []string{"foo", "bar", "baz", "qux"},
+		match:   false,
+	},
+}
+
+func TestMatchPath(t *testing.T) {
+	Convey("Match path patterns", t, func() {
+		for _, test := range testMatchPaths {
+			Convey(fmt.Sprintf("Matching %s against %v -> %v", test.pattern, test.path, test.match), func() {
+				So(matchPath(test.pattern, test.path), ShouldEqual, test.match)
+			})
+		}
+	})
+}
@@ -2,6 +2,7 @@ package reflekt
 
 import (
 	"reflect"
+	"strings"
 	"github.com/davecgh/go-spew/spew"
 )
 
@@ -45,6 +46,18 @@ func (this *Value) InterfaceMap() map[string]interface{} {
 	return AsInterfaceMap(this.v)
 }
 
+// Get returns the value at the dotted path in nested maps, structs and slices, or a nil value
+func (this *Value) Get(path string) *Value {
+	var p []string
+	if path != "" {
+		p = strings.Split(path, ".")
+	}
+	if r := pathValue(reflect.ValueOf(this.v), p); r.IsValid() && r.CanInterface() {
+		return NewValue(r.Interface())
+	}
+	return NewValue(nil)
+}
+
 
 func (this *Value) Int() int {
 	return AsInt(this.v)
@@ -0,0 +1,40 @@
+package reflekt
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"time"
+)
+
+type gobValue struct {
+	V interface{}
+}
+
+func init() {
+	gob.Register(map[string]interface{}{})
+	gob.Register(map[interface{}]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+	gob.Register(json.Number(""))
+}
+
+// GobEncode implements gob.GobEncoder. The dynamic type of the wrapped value is preserved,
+// which requires custom types contained in interfaces to be registered with gob.Register.
+func (this *Value) GobEncode() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(&gobValue{this.v}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder
+func (this *Value) GobDecode(raw []byte) error {
+	v := &gobValue{}
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(v); err != nil {
+		return err
+	}
+	this.Set(v.V)
+	return nil
+}
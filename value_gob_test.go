@@ -0,0 +1,61 @@
+package reflekt
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+type tGob struct {
+	Name string
+	Tags []string
+}
+
+func init() {
+	gob.Register(tGob{})
+}
+
+var testsTestValue_Gob = []interface{}{
+	nil,
+	1,
+	int8(-2),
+	uint64(1 << 63),
+	float32(1.5),
+	"foo",
+	true,
+	[]byte("bar"),
+	[]int{1, 2},
+	[]interface{}{1, "2", 3.0, nil},
+	map[string]interface{}{"a": int64(1), "b": []interface{}{"c", map[string]interface{}{"d": uint8(4)}}},
+	map[interface{}]interface{}{"a": 1, 2: "b"},
+	time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	tGob{Name: "foo", Tags: []string{"x"}},
+}
+
+func TestValue_Gob(t *testing.T) {
+	Convey("Encode and decode gob preserving types", t, func() {
+		for i, test := range testsTestValue_Gob {
+			Convey(fmt.Sprintf("(%d) From %s", i+1, typeName(test)), func() {
+				buf := new(bytes.Buffer)
+				So(gob.NewEncoder(buf).Encode(NewValue(test)), ShouldBeNil)
+				to := NewValue(nil)
+				So(gob.NewDecoder(buf).Decode(to), ShouldBeNil)
+				So(to.Interface(), ShouldResemble, test)
+			})
+		}
+	})
+	Convey("Encode values nested in structs", t, func() {
+		type wrap struct {
+			Values []*Value
+		}
+		buf := new(bytes.Buffer)
+		So(gob.NewEncoder(buf).Encode(wrap{[]*Value{NewValue(1), NewValue("x")}}), ShouldBeNil)
+		to := wrap{}
+		So(gob.NewDecoder(buf).Decode(&to), ShouldBeNil)
+		So(to.Values[0].Interface(), ShouldEqual, 1)
+		So(to.Values[1].Interface(), ShouldEqual, "x")
+	})
+}
@@ -1,13 +1,64 @@
 package reflekt
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 )
 
 func (this *Value) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.v)
 }
 
+// UnmarshalJSON implements json.Unmarshaler. Numbers are decoded as json.Number, so that
+// integers beyond the precision of float64 are preserved and converted exactly by Int, Float
+// and String.
 func (this *Value) UnmarshalJSON(raw []byte) error {
-	return json.Unmarshal(raw, &this.v)
-}
\ No newline at end of file
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return err
+	} else if _, err := d.Token(); err != io.EOF {
+		return fmt.Errorf("Unexpected data after JSON value")
+	}
+	this.Set(v)
+	return nil
+}
+
+// JSONStream decodes a sequence of JSON values, as in newline delimited JSON, into Values
+type JSONStream struct {
+	dec *json.Decoder
+}
+
+// NewJSONStream creates a stream decoding JSON values from the reader
+func NewJSONStream(r io.Reader) *JSONStream {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return &JSONStream{d}
+}
+
+// Next returns the next decoded value or io.EOF if the reader is exhausted
+func (this *JSONStream) Next() (*Value, error) {
+	var v interface{}
+	if err := this.dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return NewValue(v), nil
+}
+
+// Each calls the function with every decoded value until the reader is exhausted or the
+// function returns an error
+func (this *JSONStream) Each(f func(*Value) error) error {
+	for {
+		v, err := this.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		} else if err = f(v); err != nil {
+			return err
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -32,7 +35,7 @@ var testsTestValue_JSON = []struct {
 			return v.InterfaceMap()
 		},
 		to: map[string]interface{}{
-			"foo": float64(1),
+			"foo": json.Number("1"),
 		},
 	},
 	{
@@ -51,6 +54,27 @@ var testsTestValue_JSON = []struct {
 		},
 		to: []int{1, 2, 3},
 	},
+	{
+		from: []byte(`9007199254740993`),
+		get: func(v *Value) interface{} {
+			return v.Int()
+		},
+		to: 9007199254740993,
+	},
+	{
+		from: []byte(`{"id":9223372036854775807}`),
+		get: func(v *Value) interface{} {
+			return AsString(v.InterfaceMap()["id"])
+		},
+		to: "9223372036854775807",
+	},
+	{
+		from: []byte(`1.25e2`),
+		get: func(v *Value) interface{} {
+			return []interface{}{v.Int(), v.Float(), v.String()}
+		},
+		to: []interface{}{125, 125.0, "1.25e2"},
+	},
 }
 
 func TestValue_JSON(t *testing.T) {
@@ -67,4 +91,43 @@ func TestValue_JSON(t *testing.T) {
 			})
 		}
 	})
+	Convey("Fail on trailing data", t, func() {
+		for _, raw := range []string{"1 garbage", "1 2", "{} {}", "[1]]"} {
+			So(NewValue(nil).UnmarshalJSON([]byte(raw)), ShouldNotBeNil)
+		}
+		So(NewValue(nil).UnmarshalJSON([]byte(" 1 \n")), ShouldBeNil)
+	})
+	Convey("Reset the reflected value", t, func() {
+		v := NewValue("foo")
+		So(v.Reflect().Kind(), ShouldEqual, reflect.String)
+		So(v.UnmarshalJSON([]byte("[1]")), ShouldBeNil)
+		So(v.Reflect().Kind(), ShouldEqual, reflect.Slice)
+	})
+}
+
+func TestJSONStream(t *testing.T) {
+	Convey("Decode newline delimited JSON", t, func() {
+		s := NewJSONStream(strings.NewReader("{\"id\":9007199254740993}\n\n[1,2]\n\"foo\"\n"))
+		v, err := s.Next()
+		So(err, ShouldBeNil)
+		So(AsInt(v.InterfaceMap()["id"]), ShouldEqual, 9007199254740993)
+		res := []interface{}{}
+		So(s.Each(func(v *Value) error {
+			res = append(res, v.Interface())
+			return nil
+		}), ShouldBeNil)
+		So(res, ShouldResemble, []interface{}{[]interface{}{json.Number("1"), json.Number("2")}, "foo"})
+		_, err = s.Next()
+		So(err, ShouldEqual, io.EOF)
+	})
+	Convey("Fail on broken JSON", t, func() {
+		s := NewJSONStream(strings.NewReader("1\n{broken\n"))
+		count := 0
+		err := s.Each(func(v *Value) error {
+			count++
+			return nil
+		})
+		So(err, ShouldNotBeNil)
+		So(count, ShouldEqual, 1)
+	})
 }
@@ -0,0 +1,66 @@
+package reflekt
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+)
+
+// sqlValue returns the underlying value of driver.Valuer implementations, like the sql.Null*
+// types, and []byte column values as string. Other values are returned as is.
+func sqlValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(x)
+	case driver.Valuer:
+		if r := reflect.ValueOf(x); r.Kind() == reflect.Ptr && r.IsNil() {
+			return nil
+		} else if dv, err := x.Value(); err == nil {
+			return sqlValue(dv)
+		}
+	case reflect.Value:
+		if !x.IsValid() || !x.CanInterface() {
+			return v
+		}
+		switch x.Interface().(type) {
+		case []byte, driver.Valuer:
+			return sqlValue(x.Interface())
+		}
+	}
+	return v
+}
+
+// Scan implements sql.Scanner, so that column values can be scanned into a Value
+func (this *Value) Scan(src interface{}) error {
+	if b, ok := src.([]byte); ok {
+		src = append([]byte{}, b...)
+	}
+	this.Set(src)
+	return nil
+}
+
+// Value implements driver.Valuer, so that a Value can be used as a query argument. Maps, slices
+// and structs are encoded as JSON, JSON numbers as int64 or float64.
+func (this *Value) Value() (driver.Value, error) {
+	if n, ok := this.v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(this.v)
+	if err == nil {
+		return v, nil
+	}
+	switch reflect.ValueOf(this.v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		raw, err := json.Marshal(this.v)
+		if err != nil {
+			return nil, err
+		}
+		return string(raw), nil
+	}
+	return nil, err
+}
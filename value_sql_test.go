@@ -0,0 +1,190 @@
+package reflekt
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver is an in-process database/sql driver returning prepared results per query
+type fakeDriver struct {
+	mu      sync.Mutex
+	results map[string]*fakeResult
+	args    [][]driver.Value
+}
+
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+type fakeRows struct {
+	res *fakeResult
+	pos int
+}
+
+var testFakeDriver = &fakeDriver{results: make(map[string]*fakeResult)}
+
+func init() {
+	sql.Register("reflekt-fake", testFakeDriver)
+}
+
+func (this *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{this}, nil
+}
+
+func (this *fakeDriver) result(query string, columns []string, rows ...[]driver.Value) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.results[query] = &fakeResult{columns, rows}
+}
+
+func (this *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{this.d, query}, nil
+}
+
+func (this *fakeConn) Close() error {
+	return nil
+}
+
+func (this *fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("Transactions not supported")
+}
+
+func (this *fakeStmt) Close() error {
+	return nil
+}
+
+func (this *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (this *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	this.d.mu.Lock()
+	defer this.d.mu.Unlock()
+	this.d.args = append(this.d.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (this *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	this.d.mu.Lock()
+	defer this.d.mu.Unlock()
+	res, ok := this.d.results[this.query]
+	if !ok {
+		return nil, fmt.Errorf("No result for %s", this.query)
+	}
+	return &fakeRows{res: res}, nil
+}
+
+func (this *fakeRows) Columns() []string {
+	return this.res.columns
+}
+
+func (this *fakeRows) Close() error {
+	return nil
+}
+
+func (this *fakeRows) Next(dest []driver.Value) error {
+	if this.pos >= len(this.res.rows) {
+		return io.EOF
+	}
+	copy(dest, this.res.rows[this.pos])
+	this.pos++
+	return nil
+}
+
+func openFakeDB() *sql.DB {
+	db, err := sql.Open("reflekt-fake", "")
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+var testsSQLCast = []struct {
+	from   interface{}
+	int    int
+	float  float64
+	bool   bool
+	string string
+}{
+	{from: sql.NullInt64{Int64: 42, Valid: true}, int: 42, float: 42, bool: true, string: "42"},
+	{from: sql.NullInt64{Int64: 42}, int: 0, float: 0, bool: false, string: ""},
+	{from: &sql.NullFloat64{Float64: 1.5, Valid: true}, int: 1, float: 1.5, bool: true, string: "1.5"},
+	{from: sql.NullBool{Bool: true, Valid: true}, int: 1, float: 1, bool: true, string: "true"},
+	{from: sql.NullString{String: "12", Valid: true}, int: 12, float: 12, bool: true, string: "12"},
+	{from: sql.NullTime{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}, string: "2024-01-02T03:04:05Z"},
+	{from: []byte("7.5"), int: 7, float: 7.5, bool: true, string: "7.5"},
+	{from: (*sql.NullInt64)(nil), int: 0, float: 0, bool: false, string: ""},
+	{from: NewValue(3), int: 3, float: 3, bool: true, string: "3"},
+}
+
+func TestSQL_Cast(t *testing.T) {
+	Convey("Cast database/sql values", t, func() {
+		for i, test := range testsSQLCast {
+			Convey(fmt.Sprintf("%d) From %s (%v)", i, typeName(test.from), test.from), func() {
+				So(AsInt(test.from), ShouldEqual, test.int)
+				So(AsFloat(test.from), ShouldEqual, test.float)
+				So(AsBool(test.from), ShouldEqual, test.bool)
+				So(AsString(test.from), ShouldEqual, test.string)
+			})
+		}
+	})
+}
+
+func TestValue_SQL(t *testing.T) {
+	db := openFakeDB()
+	defer db.Close()
+	testFakeDriver.result("SELECT value_sql", []string{"id", "name", "score", "missing"},
+		[]driver.Value{int64(1), []byte("foo"), float64(1.5), nil},
+	)
+
+	Convey("Scan column values into Value", t, func() {
+		row := db.QueryRow("SELECT value_sql")
+		id, name, score, missing := NewValue(nil), NewValue(nil), NewValue(nil), NewValue(nil)
+		So(row.Scan(id, name, score, missing), ShouldBeNil)
+		So(id.Int(), ShouldEqual, 1)
+		So(id.String(), ShouldEqual, "1")
+		So(name.String(), ShouldEqual, "foo")
+		So(score.Float(), ShouldEqual, 1.5)
+		So(score.Int(), ShouldEqual, 1)
+		So(missing.Interface(), ShouldBeNil)
+		So(missing.String(), ShouldEqual, "")
+	})
+
+	Convey("Scan column values into map and cast", t, func() {
+		rows, err := db.Query("SELECT value_sql")
+		So(err, ShouldBeNil)
+		defer rows.Close()
+		So(rows.Next(), ShouldBeTrue)
+		var id sql.NullInt64
+		var score sql.NullFloat64
+		var name sql.NullString
+		var missing sql.NullString
+		So(rows.Scan(&id, &name, &score, &missing), ShouldBeNil)
+		m := map[string]interface{}{"id": id, "name": name, "score": score, "missing": missing}
+		So(AsIntMap(m), ShouldResemble, map[string]int{"id": 1, "name": 0, "score": 1, "missing": 0})
+		So(AsStringMap(m), ShouldResemble, map[string]string{"id": "1", "name": "foo", "score": "1.5", "missing": ""})
+	})
+
+	Convey("Use Value as query argument", t, func() {
+		_, err := db.Exec("INSERT", NewValue(uint8(3)), NewValue("foo"), NewValue(map[string]int{"a": 1}), NewValue(nil))
+		So(err, ShouldBeNil)
+		args := testFakeDriver.args[len(testFakeDriver.args)-1]
+		So(args, ShouldResemble, []driver.Value{int64(3), "foo", `{"a":1}`, nil})
+	})
+}
@@ -0,0 +1,26 @@
+package reflekt
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// MarshalText implements encoding.TextMarshaler. Scalars and times are formatted with AsString, maps,
+// slices and structs are encoded as JSON.
+func (this *Value) MarshalText() ([]byte, error) {
+	if this.v == nil || IsScalar(this.v) || reflect.TypeOf(this.v) == timeType {
+		return []byte(AsString(this.v)), nil
+	}
+	return json.Marshal(this.v)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. JSON objects and arrays are decoded, any
+// other text is converted with Infer.
+func (this *Value) UnmarshalText(text []byte) error {
+	if t := bytes.TrimSpace(text); len(t) > 0 && (t[0] == '{' || t[0] == '[') && json.Valid(t) {
+		return this.UnmarshalJSON(t)
+	}
+	this.Set(Infer(string(text)))
+	return nil
+}
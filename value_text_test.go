@@ -0,0 +1,46 @@
+package reflekt
+
+import (
+	"encoding/json"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+var testsTestValue_Text = []struct {
+	from string
+	to   interface{}
+	text string
+}{
+	{from: "", to: "", text: ""},
+	{from: "foo", to: "foo", text: "foo"},
+	{from: "42", to: int64(42), text: "42"},
+	{from: "4.5", to: 4.5, text: "4.5"},
+	{from: "true", to: true, text: "true"},
+	{from: "null", to: nil, text: ""},
+	{from: "2024-01-02T03:04:05Z", to: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), text: "2024-01-02T03:04:05Z"},
+	{from: `{"a":1}`, to: map[string]interface{}{"a": json.Number("1")}, text: `{"a":1}`},
+	{from: `[1,"b"]`, to: []interface{}{json.Number("1"), "b"}, text: `[1,"b"]`},
+	{from: `[broken`, to: "[broken", text: "[broken"},
+}
+
+func TestValue_Text(t *testing.T) {
+	Convey("Marshal and unmarshal text", t, func() {
+		for i, test := range testsTestValue_Text {
+			Convey(fmt.Sprintf("(%d) From %q", i+1, test.from), func() {
+				v := NewValue(nil)
+				So(v.UnmarshalText([]byte(test.from)), ShouldBeNil)
+				So(v.Interface(), ShouldResemble, test.to)
+				raw, err := v.MarshalText()
+				So(err, ShouldBeNil)
+				So(string(raw), ShouldEqual, test.text)
+			})
+		}
+	})
+	Convey("Encode embedded values as JSON, not as text", t, func() {
+		raw, err := json.Marshal(&struct{ V Value }{*NewValue(map[string]int{"x": 1})})
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, `{"V":{"x":1}}`)
+	})
+}
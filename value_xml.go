@@ -0,0 +1,156 @@
+package reflekt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MarshalXML implements xml.Marshaler. Maps (and structs, via StructAsMap) become elements
+// with attributes from `@` prefixed keys, text from the `#text` key and child elements from all
+// other keys, in sorted order. Slices in maps become repeated child elements, slices elsewhere
+// repeated `item` child elements. Scalars become text. Fails on keys which are not valid XML names,
+// like numeric keys.
+func (this *Value) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return xmlEncode(e, start, this.v)
+}
+
+// UnmarshalXML implements xml.Unmarshaler. An element without attributes and child elements
+// becomes its text as string. Otherwise it becomes a map[string]interface{} with attributes
+// under `@` prefixed keys, non-whitespace text under `#text` and child elements under their
+// local name, collected into an []interface{} if they occur multiple times.
+func (this *Value) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := xmlDecode(d, start)
+	if err != nil {
+		return err
+	}
+	this.Set(v)
+	return nil
+}
+
+func xmlDecode(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	m := make(map[string]interface{})
+	for _, a := range start.Attr {
+		m["@"+a.Name.Local] = a.Value
+	}
+	text := ""
+	children := false
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			children = true
+			v, err := xmlDecode(d, t)
+			if err != nil {
+				return nil, err
+			}
+			n := t.Name.Local
+			if prev, ok := m[n]; !ok {
+				m[n] = v
+			} else if s, ok := prev.([]interface{}); ok {
+				m[n] = append(s, v)
+			} else {
+				m[n] = []interface{}{prev, v}
+			}
+		case xml.CharData:
+			text += string(t)
+		case xml.EndElement:
+			if !children && len(start.Attr) == 0 {
+				return text, nil
+			} else if t := strings.TrimSpace(text); t != "" {
+				m["#text"] = t
+			}
+			return m, nil
+		}
+	}
+}
+
+// xmlIsName checks whether the name is a valid XML name without namespace prefix
+func xmlIsName(n string) bool {
+	if n == "" {
+		return false
+	}
+	for i, c := range n {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c) && c != '-' && c != '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func xmlIsList(r reflect.Value) bool {
+	return isListKind(r.Kind()) && r.Type().Elem().Kind() != reflect.Uint8
+}
+
+func xmlEncode(e *xml.Encoder, start xml.StartElement, v interface{}) error {
+	r := indirect(reflect.ValueOf(v))
+	if r.Kind() == reflect.Struct && r.Type() != timeType {
+		r = reflect.ValueOf(StructAsMap(r.Interface()))
+	}
+	switch {
+	case r.Kind() == reflect.Map:
+		m := AsInterfaceMap(r.Interface())
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if n := strings.TrimPrefix(k, "@"); k != "#text" && !xmlIsName(n) {
+				return fmt.Errorf("Cannot encode %s as XML name", strconv.Quote(n))
+			} else if strings.HasPrefix(k, "@") {
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: k[1:]}, Value: AsString(m[k])})
+			}
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		if t, ok := m["#text"]; ok {
+			if err := e.EncodeToken(xml.CharData(AsString(t))); err != nil {
+				return err
+			}
+		}
+		for _, k := range keys {
+			if strings.HasPrefix(k, "@") || k == "#text" {
+				continue
+			}
+			child := xml.StartElement{Name: xml.Name{Local: k}}
+			if c := indirect(reflect.ValueOf(m[k])); c.IsValid() && xmlIsList(c) {
+				for i := 0; i < c.Len(); i++ {
+					if err := xmlEncode(e, child, c.Index(i).Interface()); err != nil {
+						return err
+					}
+				}
+			} else if err := xmlEncode(e, child, m[k]); err != nil {
+				return err
+			}
+		}
+	case r.IsValid() && xmlIsList(r):
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		child := xml.StartElement{Name: xml.Name{Local: "item"}}
+		for i := 0; i < r.Len(); i++ {
+			if err := xmlEncode(e, child, r.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		if r.IsValid() {
+			if err := e.EncodeToken(xml.CharData(AsString(r))); err != nil {
+				return err
+			}
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,146 @@
+package reflekt
+
+import (
+	"encoding/xml"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+var testsTestValue_XML = []struct {
+	from []byte
+	get  func(v *Value) interface{}
+	to   interface{}
+}{
+	{
+		from: []byte(`<Value>123</Value>`),
+		get: func(v *Value) interface{} {
+			return v.Int()
+		},
+		to: 123,
+	},
+	{
+		from: []byte(`<Value></Value>`),
+		get: func(v *Value) interface{} {
+			return v.Interface()
+		},
+		to: "",
+	},
+	{
+		from: []byte(`<Value><bar>baz</bar><foo>1</foo></Value>`),
+		get: func(v *Value) interface{} {
+			return v.InterfaceMap()
+		},
+		to: map[string]interface{}{
+			"foo": "1",
+			"bar": "baz",
+		},
+	},
+	{
+		from: []byte(`<Value id="7" type="x">text</Value>`),
+		get: func(v *Value) interface{} {
+			return v.StringMap()
+		},
+		to: map[string]string{
+			"@id":   "7",
+			"@type": "x",
+			"#text": "text",
+		},
+	},
+	{
+		from: []byte(`<Value><n>1</n><n>2</n><n>3</n></Value>`),
+		get: func(v *Value) interface{} {
+			return NewValue(v.InterfaceMap()["n"]).Ints()
+		},
+		to: []int{1, 2, 3},
+	},
+	{
+		from: []byte(`<Value><a id="1"><b>x</b></a></Value>`),
+		get: func(v *Value) interface{} {
+			return v.Interface()
+		},
+		to: map[string]interface{}{
+			"a": map[string]interface{}{
+				"@id": "1",
+				"b":   "x",
+			},
+		},
+	},
+}
+
+func TestValue_XML(t *testing.T) {
+	Convey("Marshal and unmarshal XML", t, func() {
+		for i, test := range testsTestValue_XML {
+			out := fmt.Sprintf("(%d) From \"%s\"", i+1, test.from)
+			Convey(out, func() {
+				to := NewValue(nil)
+				err := xml.Unmarshal(test.from, to)
+				So(err, ShouldBeNil)
+				So(test.get(to), ShouldResemble, test.to)
+				raw, err := xml.Marshal(to)
+				So(err, ShouldBeNil)
+				So(string(raw), ShouldEqual, string(test.from))
+			})
+		}
+	})
+}
+
+type tXML struct {
+	Name  string
+	Tags  []string
+	Score float64
+}
+
+var testsTestValue_MarshalXML = []struct {
+	from interface{}
+	to   string
+}{
+	{
+		from: nil,
+		to:   `<Value></Value>`,
+	},
+	{
+		from: true,
+		to:   `<Value>true</Value>`,
+	},
+	{
+		from: []int{1, 2},
+		to:   `<Value><item>1</item><item>2</item></Value>`,
+	},
+	{
+		from: map[string]interface{}{"b": "<&>", "a": []int{1, 2}, "@c": 3},
+		to:   `<Value c="3"><a>1</a><a>2</a><b>&lt;&amp;&gt;</b></Value>`,
+	},
+	{
+		from: &tXML{Name: "foo", Tags: []string{"x", "y"}, Score: 1.5},
+		to:   `<Value><Name>foo</Name><Score>1.5</Score><Tags>x</Tags><Tags>y</Tags></Value>`,
+	},
+}
+
+func TestValue_MarshalXML(t *testing.T) {
+	Convey("Marshal values to XML", t, func() {
+		for i, test := range testsTestValue_MarshalXML {
+			Convey(fmt.Sprintf("(%d) From %s", i+1, typeName(test.from)), func() {
+				raw, err := xml.Marshal(NewValue(test.from))
+				So(err, ShouldBeNil)
+				So(string(raw), ShouldEqual, test.to)
+			})
+		}
+	})
+	Convey("Fail on invalid names", t, func() {
+		for _, k := range []string{"1", "a b", "", "@", "@1", "-a", "a:b", "<a>"} {
+			_, err := xml.Marshal(NewValue(map[string]interface{}{k: 1}))
+			So(err, ShouldNotBeNil)
+		}
+		_, err := xml.Marshal(NewValue(map[string]interface{}{"a": map[string]interface{}{"1": 2}}))
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Marshal value in struct", t, func() {
+		raw, err := xml.Marshal(struct {
+			XMLName xml.Name `xml:"envelope"`
+			Body    *Value   `xml:"body"`
+		}{Body: NewValue(map[string]interface{}{"id": 1})})
+		So(err, ShouldBeNil)
+		So(string(raw), ShouldEqual, `<envelope><body><id>1</id></body></envelope>`)
+	})
+}
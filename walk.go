@@ -0,0 +1,269 @@
+package reflekt
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	// SkipNode can be returned by a Visitor to not descend into the children of the current node
+	SkipNode = errors.New("Skip node")
+
+	// StopWalk can be returned by a Visitor to end the walk immediately without an error
+	StopWalk = errors.New("Stop walk")
+)
+
+// WalkNode is a single node in a value graph, as handed to a Visitor
+type WalkNode struct {
+
+	// Path contains the map keys, slice indices and struct field names leading to this node.
+	// Pointers and interfaces do not add a segment
+	Path []string
+
+	// Field is set if the node is a struct field, or the pointed to or contained value of one
+	Field *reflect.StructField
+
+	// Depth is the amount of hops from the root, including pointer and interface indirections
+	Depth int
+
+	// Value is the reflected value of the node
+	Value reflect.Value
+
+	slot reflect.Type
+	set  func(reflect.Value)
+}
+
+// Visitor is called for each node by Walk. Returning SkipNode or StopWalk controls the walk,
+// any other error aborts it and is returned from Walk
+type Visitor func(n *WalkNode) error
+
+// PathString returns the path joined with dots
+func (this *WalkNode) PathString() string {
+	return strings.Join(this.Path, ".")
+}
+
+// Key returns the last path segment or an empty string for the root
+func (this *WalkNode) Key() string {
+	if len(this.Path) == 0 {
+		return ""
+	}
+	return this.Path[len(this.Path)-1]
+}
+
+// CanReplace returns whether the node can be replaced in place
+func (this *WalkNode) CanReplace() bool {
+	return this.set != nil
+}
+
+// Replace exchanges the value of the node in its parent. The walk continues with the
+// children of the new value.
+func (this *WalkNode) Replace(v interface{}) error {
+	if this.set == nil {
+		return fmt.Errorf("Cannot replace node %s (%s) in place", this.PathString(), this.Value.Kind())
+	}
+	r, err := assignable(reflectValue(v), this.slot)
+	if err != nil {
+		return fmt.Errorf("Cannot replace node %s: %s", this.PathString(), err)
+	}
+	this.set(r)
+	this.Value = r
+	return nil
+}
+
+// assignable returns v so that it can be assigned to a value of type t
+func assignable(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	switch {
+	case !v.IsValid():
+		return reflect.Zero(t), nil
+	case v.Type().AssignableTo(t):
+		return v, nil
+	case !v.Type().ConvertibleTo(t):
+		break
+	case v.Kind() == t.Kind():
+		return v.Convert(t), nil
+	case IsNumericKind(v.Kind()) && IsNumericKind(t.Kind()):
+		return v.Convert(t), nil
+	}
+	return v, fmt.Errorf("Value of type %s is not assignable to %s", v.Type(), t)
+}
+
+type walkRef struct {
+	p uintptr
+	l int
+	t reflect.Type
+}
+
+type walker struct {
+	visit Visitor
+	seen  map[walkRef]bool
+}
+
+// Walk visits every node of the value graph in v, depth-first and parents before children.
+// Map keys are visited in sorted order. Pointers, maps and slices which are already on the
+// current path are not descended into again, so cyclic structures are visited once.
+// Unexported struct fields are not visited. To replace nodes in place, v must be a pointer or a
+// map or slice. Structs and arrays in maps and interfaces are walked as copies, which are written
+// back after their children have been visited.
+func Walk(v interface{}, visit Visitor) error {
+	w := &walker{
+		visit: visit,
+		seen:  make(map[walkRef]bool),
+	}
+	r := reflectValue(v)
+	if !r.IsValid() {
+		return nil
+	}
+	err := w.walk(&WalkNode{Path: []string{}, Value: r, slot: r.Type()})
+	if err == StopWalk {
+		return nil
+	}
+	return err
+}
+
+func (this *walker) enter(ref walkRef) bool {
+	if this.seen[ref] {
+		return false
+	}
+	this.seen[ref] = true
+	return true
+}
+
+func (this *walker) child(n *WalkNode, seg string, v reflect.Value, slot reflect.Type, set func(reflect.Value)) *WalkNode {
+	p := n.Path
+	if seg != "" {
+		p = appendPath(n.Path, seg)
+	}
+	return &WalkNode{Path: p, Depth: n.Depth + 1, Value: v, slot: slot, set: set}
+}
+
+func settable(v reflect.Value) func(reflect.Value) {
+	if v.CanSet() {
+		return v.Set
+	}
+	return nil
+}
+
+func (this *walker) walk(n *WalkNode) error {
+	if err := this.visit(n); err == SkipNode {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	r := n.Value
+	if (r.Kind() == reflect.Struct || r.Kind() == reflect.Array) && !r.CanSet() && n.set != nil {
+		// map values and values in interfaces are not settable, so a copy is walked and written back
+		cp := reflect.New(r.Type()).Elem()
+		cp.Set(r)
+		err := this.children(n, cp)
+		if err == nil || err == StopWalk {
+			n.set(cp)
+		}
+		return err
+	}
+	return this.children(n, r)
+}
+
+func (this *walker) children(n *WalkNode, r reflect.Value) error {
+	switch r.Kind() {
+	case reflect.Ptr:
+		if r.IsNil() {
+			return nil
+		}
+		ref := walkRef{r.Pointer(), 0, r.Type()}
+		if !this.enter(ref) {
+			return nil
+		}
+		defer delete(this.seen, ref)
+		e := r.Elem()
+		c := this.child(n, "", e, e.Type(), settable(e))
+		c.Field = n.Field
+		return this.walk(c)
+	case reflect.Interface:
+		if r.IsNil() {
+			return nil
+		}
+		c := this.child(n, "", r.Elem(), n.slot, n.set)
+		c.Field = n.Field
+		return this.walk(c)
+	case reflect.Map:
+		if r.IsNil() {
+			return nil
+		}
+		ref := walkRef{r.Pointer(), 0, r.Type()}
+		if !this.enter(ref) {
+			return nil
+		}
+		defer delete(this.seen, ref)
+		keys := r.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprintf("%v", k.Interface())
+		}
+		sort.Sort(&keySorter{keys, names})
+		et := r.Type().Elem()
+		for i, k := range keys {
+			k := k
+			set := func(v reflect.Value) {
+				r.SetMapIndex(k, v)
+			}
+			if err := this.walk(this.child(n, names[i], r.MapIndex(k), et, set)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if r.Kind() == reflect.Slice {
+			if r.IsNil() {
+				return nil
+			}
+			ref := walkRef{r.Pointer(), r.Len(), r.Type()}
+			if !this.enter(ref) {
+				return nil
+			}
+			defer delete(this.seen, ref)
+		}
+		et := r.Type().Elem()
+		for i := 0; i < r.Len(); i++ {
+			e := r.Index(i)
+			if err := this.walk(this.child(n, fmt.Sprintf("%d", i), e, et, settable(e))); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := r.Type()
+		for i := 0; i < r.NumField(); i++ {
+			ft := t.Field(i)
+			if ft.PkgPath != "" {
+				continue
+			}
+			f := r.Field(i)
+			c := this.child(n, ft.Name, f, ft.Type, settable(f))
+			c.Field = &ft
+			if err := this.walk(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type keySorter struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (this *keySorter) Len() int {
+	return len(this.keys)
+}
+
+func (this *keySorter) Less(i, j int) bool {
+	return this.names[i] < this.names[j]
+}
+
+func (this *keySorter) Swap(i, j int) {
+	this.keys[i], this.keys[j] = this.keys[j], this.keys[i]
+	this.names[i], this.names[j] = this.names[j], this.names[i]
+}
@@ -0,0 +1,202 @@
+package reflekt
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tWalk struct {
+	A int
+	B []string
+	C map[string]interface{}
+	D *tWalk
+	e int
+}
+
+var testsWalk = []struct {
+	from  interface{}
+	paths []string
+}{
+	{
+		from:  nil,
+		paths: []string{},
+	},
+	{
+		from:  1,
+		paths: []string{":int:0"},
+	},
+	{
+		from:  []int{1, 2},
+		paths: []string{":slice:0", "0:int:1", "1:int:1"},
+	},
+	{
+		from:  map[string]interface{}{"b": 1, "a": []interface{}{"x"}},
+		paths: []string{":map:0", "a:interface:1", "a:slice:2", "a.0:interface:3", "a.0:string:4", "b:interface:1", "b:int:2"},
+	},
+	{
+		from: &tWalk{A: 1, B: []string{"x"}, e: 2},
+		paths: []string{
+			":ptr:0", ":struct:1", "A:int:2", "B:slice:2", "B.0:string:3", "C:map:2", "D:ptr:2",
+		},
+	},
+}
+
+func TestWalk(t *testing.T) {
+	Convey("Walk value graphs", t, func() {
+		for i, test := range testsWalk {
+			Convey(fmt.Sprintf("%d) From %s", i, typeName(test.from)), func() {
+				paths := []string{}
+				err := Walk(test.from, func(n *WalkNode) error {
+					paths = append(paths, fmt.Sprintf("%s:%s:%d", n.PathString(), n.Value.Kind(), n.Depth))
+					return nil
+				})
+				So(err, ShouldBeNil)
+				So(paths, ShouldResemble, test.paths)
+			})
+		}
+	})
+}
+
+func TestWalk_Control(t *testing.T) {
+	v := map[string]interface{}{"a": []int{1, 2}, "b": 2, "c": 3}
+	Convey("Skip subtrees", t, func() {
+		paths := []string{}
+		err := Walk(v, func(n *WalkNode) error {
+			paths = append(paths, n.PathString())
+			if n.Key() == "a" {
+				return SkipNode
+			}
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(paths, ShouldResemble, []string{"", "a", "b", "b", "c", "c"})
+	})
+	Convey("Stop walking", t, func() {
+		paths := []string{}
+		err := Walk(v, func(n *WalkNode) error {
+			paths = append(paths, n.PathString())
+			if n.Key() == "b" {
+				return StopWalk
+			}
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(paths, ShouldResemble, []string{"", "a", "a", "a.0", "a.1", "b"})
+	})
+	Convey("Return errors", t, func() {
+		err := Walk(v, func(n *WalkNode) error {
+			if n.PathString() == "a.1" {
+				return fmt.Errorf("Failed at %s", n.PathString())
+			}
+			return nil
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "Failed at a.1")
+	})
+	Convey("Provide struct fields", t, func() {
+		fields := []string{}
+		Walk(tWalk{A: 1}, func(n *WalkNode) error {
+			if n.Field != nil {
+				fields = append(fields, n.Field.Name)
+			}
+			return nil
+		})
+		So(fields, ShouldResemble, []string{"A", "B", "C", "D"})
+	})
+}
+
+func TestWalk_Cycles(t *testing.T) {
+	Convey("Detect pointer cycles", t, func() {
+		v := &tWalk{A: 1}
+		v.D = v
+		count := 0
+		err := Walk(v, func(n *WalkNode) error {
+			count++
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 6)
+	})
+	Convey("Detect map cycles", t, func() {
+		v := map[string]interface{}{}
+		v["self"] = v
+		count := 0
+		err := Walk(v, func(n *WalkNode) error {
+			count++
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(count, ShouldEqual, 3)
+	})
+}
+
+func TestWalk_Replace(t *testing.T) {
+	Convey("Replace values in place", t, func() {
+		v := &tWalk{
+			A: 1,
+			B: []string{" foo ", "bar "},
+			C: map[string]interface{}{"x": " baz", "y": []interface{}{" qux"}},
+		}
+		err := Walk(v, func(n *WalkNode) error {
+			if n.Value.Kind() == reflect.String {
+				return n.Replace(strings.TrimSpace(n.Value.String()))
+			} else if n.Value.Kind() == reflect.Int {
+				return n.Replace(int64(n.Value.Int() + 1))
+			}
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(v, ShouldResemble, &tWalk{
+			A: 2,
+			B: []string{"foo", "bar"},
+			C: map[string]interface{}{"x": "baz", "y": []interface{}{"qux"}},
+		})
+	})
+	Convey("Descend into replaced values", t, func() {
+		v := map[string]interface{}{"a": 1}
+		paths := []string{}
+		err := Walk(v, func(n *WalkNode) error {
+			paths = append(paths, n.PathString())
+			if n.Key() == "a" && n.Value.Kind() == reflect.Interface {
+				return n.Replace([]int{1, 2})
+			}
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(paths, ShouldResemble, []string{"", "a", "a.0", "a.1"})
+		So(v, ShouldResemble, map[string]interface{}{"a": []int{1, 2}})
+	})
+	Convey("Replace values of structs in maps", t, func() {
+		v := map[string]tWalk{"x": {A: 1}}
+		err := Walk(v, func(n *WalkNode) error {
+			if n.Key() == "A" {
+				return n.Replace(2)
+			}
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(v, ShouldResemble, map[string]tWalk{"x": {A: 2}})
+	})
+	Convey("Refuse to replace unaddressable values", t, func() {
+		err := Walk(tWalk{A: 1}, func(n *WalkNode) error {
+			if n.Key() == "A" {
+				So(n.CanReplace(), ShouldBeFalse)
+				return n.Replace(2)
+			}
+			return nil
+		})
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Refuse to replace with incompatible types", t, func() {
+		err := Walk(&tWalk{A: 1}, func(n *WalkNode) error {
+			if n.Key() == "A" {
+				return n.Replace("foo")
+			}
+			return nil
+		})
+		So(err, ShouldNotBeNil)
+	})
+}